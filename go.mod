@@ -3,14 +3,15 @@ module github.com/rprtr258/lish
 go 1.22.2
 
 require (
+	github.com/BurntSushi/toml v0.3.0
 	github.com/chzyer/readline v1.5.1
 	github.com/rprtr258/fun v0.0.15
 	github.com/stretchr/testify v1.8.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
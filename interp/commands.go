@@ -0,0 +1,211 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// commandTable caches paths of executables found in PATH, like shell hash
+// table. It is dropped when PATH changes or by rehash.
+type commandTable struct {
+	mu    sync.Mutex
+	path  string            // PATH value cache is built for
+	paths map[string]string // name -> path of executable
+	names []string          // all executables in PATH, lazily listed
+}
+
+var commands commandTable
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// reset drops cache if it was built for another PATH, must be called under lock
+func (t *commandTable) reset(path string) {
+	if t.paths != nil && t.path == path {
+		return
+	}
+	t.path = path
+	t.paths = map[string]string{}
+	t.names = nil
+}
+
+func (t *commandTable) rehash() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paths = nil
+}
+
+// lookup finds executable by name in PATH, names with slash are resolved
+// against current directory instead
+func (t *commandTable) lookup(scope *shellScope, name string) (string, bool) {
+	if strings.Contains(name, "/") {
+		path, err := scope.resolvePath(name)
+		if err != nil || !isExecutable(path) {
+			return "", false
+		}
+		return path, true
+	}
+
+	pathEnv, _ := scope.getenv("PATH")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reset(pathEnv)
+
+	if path, ok := t.paths[name]; ok {
+		return path, true
+	}
+
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			dir = "."
+		}
+		path := filepath.Join(dir, name)
+		if !filepath.IsAbs(path) {
+			var err error
+			if path, err = scope.resolvePath(path); err != nil {
+				continue
+			}
+		}
+		if isExecutable(path) {
+			t.paths[name] = path
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// executables lists names of all executables in PATH
+func (t *commandTable) executables(scope *shellScope) []string {
+	pathEnv, _ := scope.getenv("PATH")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reset(pathEnv)
+
+	if t.names == nil {
+		t.names = []string{}
+		for _, dir := range filepath.SplitList(pathEnv) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if isExecutable(filepath.Join(dir, entry.Name())) {
+					t.names = append(t.names, entry.Name())
+				}
+			}
+		}
+		slices.Sort(t.names)
+		t.names = slices.Compact(t.names)
+	}
+	return t.names
+}
+
+// levenshtein returns edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// suggest returns up to 3 candidates closest to name
+func suggest(name string, candidates []string) []string {
+	maxDistance := max(1, min(3, len([]rune(name))/3))
+	type scored struct {
+		name     string
+		distance int
+	}
+	res := []scored{}
+	for _, candidate := range candidates {
+		if d := levenshtein(name, candidate); d <= maxDistance && candidate != name {
+			res = append(res, scored{candidate, d})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b scored) int { return a.distance - b.distance })
+	names := []string{}
+	for _, s := range res {
+		if !slices.Contains(names, s.name) {
+			names = append(names, s.name)
+		}
+		if len(names) == 3 {
+			break
+		}
+	}
+	return names
+}
+
+// commandNotFound makes error for unknown command with suggestions of
+// similar commands and defined symbols
+func commandNotFound(env Env, name string) error {
+	candidates := slices.Clone(commands.executables(env.scope))
+	for e := &env; ; e = e.Outer.Value {
+		for symbol := range e.bindings() {
+			candidates = append(candidates, string(symbol))
+		}
+		if !e.Outer.Valid {
+			break
+		}
+	}
+
+	if similar := suggest(name, candidates); len(similar) > 0 {
+		return fmt.Errorf("command not found: %s, did you mean: %s?", name, strings.Join(similar, ", "))
+	}
+	return fmt.Errorf("command not found: %s", name)
+}
+
+// describe tells what name is bound to, as type builtin does
+func describe(env Env, name string) (string, bool) {
+	if _, ok := specialForms[Symbol(name)]; ok {
+		return name + " is a special form", true
+	}
+
+	if a, ok := env.get(Symbol(name)); ok {
+		return name + " is " + describeAtom(a), true
+	}
+
+	if path, ok := commands.lookup(env.scope, name); ok {
+		return name + " is " + path, true
+	}
+
+	return "", false
+}
+
+func describeAtom(a Atom) string {
+	switch a.Kind {
+	case AtomKindFunc:
+		if a.Value.(Func).closure != nil {
+			// compiled by bytecode evaluator
+			return "a lambda"
+		}
+		return "a builtin"
+	case AtomKindLambda:
+		if a.Value.(Lambda).isMacro {
+			return "a macro"
+		}
+		return "a lambda"
+	default:
+		return "a " + string(a.Kind)
+	}
+}
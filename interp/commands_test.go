@@ -0,0 +1,43 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"ls", "ls", 0},
+		{"ls", "lss", 1},
+		{"gerp", "grep", 2},
+		{"kitten", "sitting", 3},
+	} {
+		assert.Equal(t, tc.d, levenshtein(tc.a, tc.b), "%s -> %s", tc.a, tc.b)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	assert.Equal(t, []string{"grep"}, suggest("gre", []string{"grep", "egrep", "cat", "grep"}))
+	assert.Equal(t, []string{"sha1sum"}, suggest("shasum", []string{"sha256sum", "sha1sum"}))
+	assert.Equal(t, []string{}, suggest("xyz", []string{"grep", "cat"}))
+}
+
+func TestCommandNotFound(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t,
+		lisherr("command not found: lish-no-such-command"),
+		eval(read("(lish-no-such-command)"), repl_env),
+	)
+	assert.Equal(t, atomString("if is a special form"), eval(read("(type 'if)"), repl_env))
+	assert.Equal(t, atomString("first is a builtin"), eval(read(`(type "first")`), repl_env))
+
+	repl_env.scope.bytecode = true
+	evalProgram(`(set double (fn (x) (* x 2)))`, repl_env)
+	assert.Equal(t, AtomKindFunc, eval(readValue(`double`), repl_env).Kind)
+	assert.Equal(t, atomString("double is a lambda"), eval(read(`(type "double")`), repl_env))
+}
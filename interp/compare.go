@@ -0,0 +1,150 @@
+package interp
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+
+	"github.com/rprtr258/fun"
+)
+
+// kindRanks order atoms of different kinds, numbers are ordered by value
+// regardless of kind
+var kindRanks = map[AtomKind]int{
+	AtomKindBool:    0,
+	AtomKindInt:     1,
+	AtomKindFloat:   1,
+	AtomKindString:  2,
+	AtomKindKeyword: 3,
+	AtomKindSymbol:  4,
+	AtomKindList:    5,
+	AtomKindHash:    6,
+	AtomKindRecord:  7,
+	AtomKindFunc:    8,
+	AtomKindLambda:  9,
+	AtomKindStream:  10,
+	AtomKindFuture:  11,
+	AtomKindError:   12,
+}
+
+// atomCmp returns -1 if a is less than b, 0 if they are equal, 1 if a is
+// greater. Atoms are totally ordered: by kind first, then by their values.
+// Ints and floats are ordered by value, ints go first if values are equal,
+// so they are never equal to floats.
+func atomCmp(a, b Atom) int {
+	if a.Kind == b.Kind {
+		return a.Value.Cmp(b.Value)
+	}
+
+	if c := cmp.Compare(kindRanks[a.Kind], kindRanks[b.Kind]); c != 0 {
+		return c
+	}
+	// int and float
+	if a.Kind == AtomKindInt {
+		if c := cmpIntFloat(a.Value.(Int), b.Value.(Float)); c != 0 {
+			return c
+		}
+		return -1
+	}
+	if c := cmpIntFloat(b.Value.(Int), a.Value.(Float)); c != 0 {
+		return -c
+	}
+	return 1
+}
+
+func atomEq(a, b Atom) bool {
+	return atomCmp(a, b) == 0
+}
+
+// cmpIntFloat compares int and float exactly, NaN is less than any number
+func cmpIntFloat(i Int, f Float) int {
+	x := float64(f)
+	switch {
+	case math.IsNaN(x):
+		return 1
+	case x >= math.MaxInt64: // 2^63, as MaxInt64 is not float64
+		return -1
+	case x < math.MinInt64:
+		return 1
+	}
+
+	whole := math.Trunc(x)
+	if c := cmp.Compare(int64(i), int64(whole)); c != 0 {
+		return c
+	}
+	return cmp.Compare(whole, x)
+}
+
+// cmpIdentity orders values of reference types by their addresses, which are
+// stable as long as values are alive. Same values are equal.
+func cmpIdentity[T any](a, b T) int {
+	return cmp.Compare(reflect.ValueOf(a).Pointer(), reflect.ValueOf(b).Pointer())
+}
+
+// hashOf returns hash of a, equal atoms have equal hashes
+func hashOf(a Atom) uint64 {
+	h := fnv.New64a()
+	writeHash(h, a)
+	return h.Sum64()
+}
+
+// writeHash writes parts of a equality depends on into h
+func writeHash(h hash.Hash64, a Atom) {
+	h.Write([]byte(a.Kind))
+	h.Write([]byte{0})
+	word := func(n uint64) {
+		h.Write(binary.LittleEndian.AppendUint64(nil, n))
+	}
+	switch v := a.Value.(type) {
+	case Bool:
+		word(fun.IF[uint64](bool(v), 1, 0))
+	case Int:
+		word(uint64(v))
+	case Float:
+		x := float64(v)
+		switch {
+		case math.IsNaN(x):
+			// all NaNs are equal
+			x = math.NaN()
+		case x == 0:
+			// -0 equals 0
+			x = 0
+		}
+		word(math.Float64bits(x))
+	case String, Keyword, Symbol, Error:
+		h.Write([]byte(v.String()))
+	case List:
+		word(uint64(len(v)))
+		for _, item := range v {
+			writeHash(h, item)
+		}
+	case Hash:
+		writeHash(h, atomList(items(a)...))
+	case Record:
+		h.Write([]byte(v.typ.name))
+		word(uint64(reflect.ValueOf(v.typ).Pointer()))
+		if _, ok := v.typ.method("="); ok {
+			// equality is up to the kind, fields can not be hashed
+			return
+		}
+		if _, ok := v.typ.method("<"); ok {
+			return
+		}
+		writeHash(h, atomList(v.fields...))
+	case Func:
+		if v.closure != nil {
+			word(uint64(reflect.ValueOf(v.closure).Pointer()))
+		} else {
+			word(uint64(reflect.ValueOf(v.meta).Pointer()))
+		}
+	case Lambda:
+		word(uint64(reflect.ValueOf(v.id).Pointer()))
+	case Stream:
+		word(uint64(reflect.ValueOf(v).Pointer()))
+	case Future:
+		word(uint64(reflect.ValueOf(v.done).Pointer()))
+	}
+}
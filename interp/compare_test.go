@@ -0,0 +1,158 @@
+package interp
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// references are values compared by identity, shared by generated atoms so
+// that the same ones meet
+var references = func() []Atom {
+	env := newEnvRepl()
+	point := &recordType{name: "Point", fields: []Symbol{"x", "y"}}
+	return []Atom{
+		namespace["+"],
+		namespace["list"],
+		eval(readValue(`(fn (x) x)`), env),
+		eval(readValue(`(fn (x) x)`), env),
+		{AtomKindStream, make(Stream)},
+		{AtomKindStream, make(Stream)},
+		{AtomKindFuture, Future{make(chan struct{}), nil}},
+		{AtomKindRecord, Record{point, []Atom{atomInt(1), atomInt(2)}, new(byte)}},
+		{AtomKindRecord, Record{point, []Atom{atomInt(1), atomFloat(2.0)}, new(byte)}},
+		{AtomKindRecord, Record{&recordType{name: "Point", fields: []Symbol{"x"}}, []Atom{atomInt(1)}, new(byte)}},
+	}
+}()
+
+// randomAtom is atom generated by testing/quick. Scalars are drawn from small
+// sets, so that equal ones are generated often.
+type randomAtom struct{ Atom }
+
+func (randomAtom) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(randomAtom{generateAtom(r, 3)})
+}
+
+var floats = []float64{0, math.Copysign(0, -1), 1, 1.5, -2, math.NaN(), math.Inf(1), math.Inf(-1), 1 << 63, -1 << 63}
+
+func generateAtom(r *rand.Rand, depth int) Atom {
+	words := []string{"", "a", "b", "ab"}
+	kinds := 10
+	if depth == 0 {
+		kinds = 7
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		return atomBool(r.Intn(2) == 0)
+	case 1:
+		return atomInt(r.Intn(5) - 2)
+	case 2:
+		return atomFloat(floats[r.Intn(len(floats))])
+	case 3:
+		return atomString(words[r.Intn(len(words))])
+	case 4:
+		return atomKeyword(words[r.Intn(len(words))])
+	case 5:
+		return []Atom{atomSymbol("a"), lisherr("a"), lisherr("b")}[r.Intn(3)]
+	case 6:
+		return references[r.Intn(len(references))]
+	case 7, 8:
+		l := make([]Atom, r.Intn(3))
+		for i := range l {
+			l[i] = generateAtom(r, depth-1)
+		}
+		return atomList(l...)
+	default:
+		h := Hash{}
+		for range r.Intn(3) {
+			h[words[r.Intn(len(words))]] = generateAtom(r, depth-1)
+		}
+		return atomHash(h)
+	}
+}
+
+// clone returns deep copy of a, references are the same
+func clone(a Atom) Atom {
+	switch v := a.Value.(type) {
+	case List:
+		l := make([]Atom, len(v))
+		for i, item := range v {
+			l[i] = clone(item)
+		}
+		return atomList(l...)
+	case Hash:
+		h := make(Hash, len(v))
+		for k, item := range v {
+			h[k] = clone(item)
+		}
+		return atomHash(h)
+	default:
+		return a
+	}
+}
+
+func TestCompareProperties(t *testing.T) {
+	config := &quick.Config{MaxCount: 5000}
+	for name, property := range map[string]any{
+		"reflexive": func(a randomAtom) bool {
+			return atomCmp(a.Atom, a.Atom) == 0 && atomCmp(a.Atom, clone(a.Atom)) == 0
+		},
+		"antisymmetric": func(a, b randomAtom) bool {
+			return atomCmp(a.Atom, b.Atom) == -atomCmp(b.Atom, a.Atom)
+		},
+		"transitive": func(a, b, c randomAtom) bool {
+			ab, bc, ac := atomCmp(a.Atom, b.Atom), atomCmp(b.Atom, c.Atom), atomCmp(a.Atom, c.Atom)
+			switch {
+			case ab <= 0 && bc <= 0:
+				return ac <= 0 && (ac < 0) == (ab < 0 || bc < 0)
+			case ab >= 0 && bc >= 0:
+				return ac >= 0 && (ac > 0) == (ab > 0 || bc > 0)
+			default:
+				return true
+			}
+		},
+		"hash_of_equal": func(a, b randomAtom) bool {
+			return hashOf(a.Atom) == hashOf(clone(a.Atom)) &&
+				(!atomEq(a.Atom, b.Atom) || hashOf(a.Atom) == hashOf(b.Atom))
+		},
+		"numbers_by_value": func(i int32, j uint8) bool {
+			// ints go before floats of the same value
+			x := atomFloat(float64(i) + float64(j)/256)
+			return atomCmp(atomInt(i), x) == -1 &&
+				(j == 0 || atomCmp(atomInt(int64(i)+1), x) == 1)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, quick.Check(property, config))
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"bool":            {`(list (< false true) (> false true))`, atomList(atomBool(true), atomBool(false))},
+		"numbers":         {`(list (< 1 1.5 2) (< -0.5 0) (< 9007199254740993 9007199254740992.0))`, atomList(atomBool(true), atomBool(true), atomBool(false))},
+		"int_float":       {`(list (= 1 1.0) (< 1 1.0))`, atomList(atomBool(false), atomBool(true))},
+		"kinds":           {`(list (< true 1) (< 1 "a") (< "a" :a) (< :a 'a) (< 'a ()) (< () {}) (< {} (fn (x) x)))`, atomList(atomBool(true), atomBool(true), atomBool(true), atomBool(true), atomBool(true), atomBool(true), atomBool(true))},
+		"list_prefix":     {`(list (< (list 1) (list 1 2)) (> (list 1 2) (list 1)) (< () (list 1)))`, atomList(atomBool(true), atomBool(true), atomBool(true))},
+		"hash":            {`(list (= {:a 1 :b 2} {:b 2 :a 1}) (< {:a 1} {:a 2}) (< {:a 9} {:b 1}) (< {:a 1} {:a 1 :b 1}))`, atomList(atomBool(true), atomBool(true), atomBool(true), atomBool(true))},
+		"functions":       {`(progn (set f (fn (x) x)) (list (= f f) (= f (fn (x) x)) (= + +) (= + -)))`, atomList(atomBool(true), atomBool(false), atomBool(true), atomBool(false))},
+		"functions_named": {`(let (h (fn (x) x)) (progn (set k h) (list (= h k) (= (hash h) (hash k)))))`, atomList(atomBool(true), atomBool(true))},
+		"stream":          {`(progn (set s (chan)) (list (= s s) (= s (chan))))`, atomList(atomBool(true), atomBool(false))},
+		"records":         {`(progn (defrecord A (x)) (defrecord B (x)) (list (< (A 2) (B 1)) (= (A 1) (A 1))))`, atomList(atomBool(true), atomBool(true))},
+		"hash_equal":      {`(list (= (hash {:a (list 1 "b")}) (hash {:a (list 1 "b")})) (= (hash 0.0) (hash -0.0)) (= (hash 1) (hash 2)))`, atomList(atomBool(true), atomBool(true), atomBool(false))},
+		"hash_key":        {`({:a 1} (list 1))`, lisherr("Hash key (1) must be string or keyword")},
+		"hash_records":    {`(progn (defrecord Id (n tag)) (extend Id Value (= (a b) (= (Id-n a) (Id-n b)))) (= (hash (Id 1 "x")) (hash (Id 1 "y"))))`, atomBool(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(readValue(tc.input), newEnvRepl()))
+		})
+	}
+}
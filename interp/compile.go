@@ -0,0 +1,475 @@
+package interp
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/rprtr258/fun"
+)
+
+// opcode is instruction of compiled lish code, see vm for what they do
+type opcode uint8
+
+const (
+	// push consts[a]
+	opConst opcode = iota
+	// push slot b of frame a levels up the closure chain
+	opLocal
+	// store top into slot b of frame a levels up, keeping it on stack
+	opSetLocal
+	// push value of symbol consts[a], evaluated as eval does
+	opGlobal
+	// push function symbol consts[a] names, command name if it is unbound
+	opCallee
+	// bind symbol consts[a] in environment to top, keeping it on stack
+	opSetGlobal
+	// name function on top as symbol consts[a]
+	opName
+	opPop
+	// jump to a
+	opJump
+	// pop, jump to a if it is falsy
+	opJumpIfFalse
+	// jump to a if top is error, keeping it
+	opJumpIfError
+	// jump to a keeping top if it is truthy and b is 1 or it is falsy and b is
+	// 0, pop it otherwise
+	opJumpIfDecided
+	// pop a values and make hash of them with keys consts[b]
+	opHash
+	// push closure of protos[a] capturing current frame
+	opClosure
+	// call function below a arguments
+	opCall
+	// call function below a arguments, returning its result
+	opTailCall
+	opReturn
+)
+
+type instr struct {
+	op   opcode
+	a, b int
+}
+
+// proto is compiled function body or top level form
+type proto struct {
+	code   []instr
+	consts []Atom
+	protos []*proto
+	// number of required parameters, they take first slots
+	params int
+	// rest arguments are bound to list in slot after required ones
+	rest bool
+	// number of slots in frame
+	slots int
+	meta  *Meta
+}
+
+// errNotCompiled is returned for forms compiler does not support, they are
+// evaluated by eval instead
+var errNotCompiled = errors.New("form is not compiled")
+
+// compiler compiles body of single function
+type compiler struct {
+	proto  *proto
+	parent *compiler
+	// env is environment macros are looked up in
+	env Env
+	// blocks are nested scopes of function, the first one has parameters,
+	// others are let bindings
+	blocks []map[Symbol]int
+	// top level form has no blocks of its own, set binds names in env
+	top bool
+	// slots of names being bound, see bind
+	pending map[int]bool
+}
+
+// compileTop compiles top level form evaluated in env
+func compileTop(ast Atom, env Env) (*proto, error) {
+	c := &compiler{proto: &proto{}, env: env, top: true}
+	if err := c.compile(ast, true); err != nil {
+		return nil, err
+	}
+	c.emit(opReturn, 0, 0)
+	return c.proto, nil
+}
+
+func (c *compiler) emit(op opcode, a, b int) int {
+	c.proto.code = append(c.proto.code, instr{op, a, b})
+	return len(c.proto.code) - 1
+}
+
+// patch makes jump at pc lead to next instruction
+func (c *compiler) patch(pcs ...int) {
+	for _, pc := range pcs {
+		c.proto.code[pc].a = len(c.proto.code)
+	}
+}
+
+func (c *compiler) constant(a Atom) int {
+	c.proto.consts = append(c.proto.consts, a)
+	return len(c.proto.consts) - 1
+}
+
+// resolve finds slot of local variable, depth is number of closures it is
+// captured through
+func (c *compiler) resolve(name Symbol) (depth, slot int, ok bool) {
+	for ; c != nil; c, depth = c.parent, depth+1 {
+		for i := len(c.blocks) - 1; i >= 0; i-- {
+			if slot, ok := c.blocks[i][name]; ok && !(depth == 0 && c.pending[slot]) {
+				return depth, slot, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// declare binds name in innermost block, reusing its slot if it is bound
+// there already
+func (c *compiler) declare(name Symbol) int {
+	block := c.blocks[len(c.blocks)-1]
+	if slot, ok := block[name]; ok {
+		return slot
+	}
+
+	block[name] = c.proto.slots
+	c.proto.slots++
+	return block[name]
+}
+
+// bind compiles value bound to name in innermost block, returning slot of
+// name. Name is declared before value is compiled, so that lambdas made by
+// value see it once they are called, as eval looks it up at that moment,
+// but value itself sees previous binding.
+func (c *compiler) bind(name Symbol, value Atom) (int, error) {
+	_, bound := c.blocks[len(c.blocks)-1][name]
+	slot := c.declare(name)
+	if !bound {
+		if c.pending == nil {
+			c.pending = map[int]bool{}
+		}
+		c.pending[slot] = true
+		defer delete(c.pending, slot)
+	}
+	return slot, c.compile(value, false)
+}
+
+// compile compiles form, leaving its value on stack. Form in tail position
+// returns its value instead.
+func (c *compiler) compile(ast Atom, tail bool) error {
+	switch ast.Kind {
+	case AtomKindSymbol:
+		name := ast.Value.(Symbol)
+		if depth, slot, ok := c.resolve(name); ok {
+			c.emit(opLocal, depth, slot)
+		} else {
+			c.emit(opGlobal, c.constant(ast), 0)
+		}
+	case AtomKindHash:
+		h := ast.Value.(Hash)
+		keys := make([]string, 0, len(h))
+		for k := range h {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if err := c.compile(h[k], false); err != nil {
+				return err
+			}
+		}
+		c.emit(opHash, len(keys), c.constant(atomList(fun.Map[Atom](atomString[string], keys...)...)))
+	case AtomKindList:
+		if len(ast.Value.(List)) == 0 {
+			c.emit(opConst, c.constant(atomNil), 0)
+			break
+		}
+		return c.compileList(ast, tail)
+	default:
+		c.emit(opConst, c.constant(ast), 0)
+	}
+
+	if tail {
+		c.emit(opReturn, 0, 0)
+	}
+	return nil
+}
+
+func (c *compiler) compileList(ast Atom, tail bool) error {
+	l := ast.Value.(List)
+	head, isSymbol := l[0].Value.(Symbol)
+	if isSymbol {
+		if _, _, ok := c.resolve(head); !ok {
+			if _, ok := specialForms[head]; ok {
+				return c.compileSpecial(head, l, tail)
+			}
+
+			if isMacroCall(ast, c.env) {
+				expanded := macroexpand(ast, c.env)
+				if expanded.Kind == AtomKindError {
+					// let eval report it
+					return errNotCompiled
+				}
+				return c.compile(expanded, tail)
+			}
+		}
+	}
+
+	// error while evaluating callee is result of the call
+	var errJump int
+	if isSymbol {
+		if depth, slot, ok := c.resolve(head); ok {
+			c.emit(opLocal, depth, slot)
+		} else {
+			c.emit(opCallee, c.constant(l[0]), 0)
+		}
+		errJump = -1
+	} else {
+		if err := c.compile(l[0], false); err != nil {
+			return err
+		}
+		errJump = c.emit(opJumpIfError, 0, 0)
+	}
+
+	for _, arg := range l[1:] {
+		if err := c.compile(arg, false); err != nil {
+			return err
+		}
+	}
+	if tail {
+		c.emit(opTailCall, len(l)-1, 0)
+	} else {
+		c.emit(opCall, len(l)-1, 0)
+	}
+
+	if errJump != -1 {
+		c.patch(errJump)
+		if tail {
+			c.emit(opReturn, 0, 0)
+		}
+	}
+	return nil
+}
+
+// compileBody compiles forms evaluated one by one, value of the last one is
+// the result, first error stops evaluation
+func (c *compiler) compileBody(body []Atom, tail bool) error {
+	if len(body) == 0 {
+		return c.compile(atomNil, tail)
+	}
+
+	errJumps := []int{}
+	for _, form := range body[:len(body)-1] {
+		if err := c.compile(form, false); err != nil {
+			return err
+		}
+		errJumps = append(errJumps, c.emit(opJumpIfError, 0, 0))
+		c.emit(opPop, 0, 0)
+	}
+	if err := c.compile(body[len(body)-1], tail); err != nil {
+		return err
+	}
+	return c.finish(tail, errJumps...)
+}
+
+// finish makes error jumps lead to the end of form, returning error if form
+// is in tail position
+func (c *compiler) finish(tail bool, errJumps ...int) error {
+	if len(errJumps) == 0 {
+		return nil
+	}
+
+	c.patch(errJumps...)
+	if tail {
+		c.emit(opReturn, 0, 0)
+	}
+	return nil
+}
+
+func (c *compiler) compileSpecial(form Symbol, l List, tail bool) error {
+	args := l[1:]
+	switch form {
+	case "quote":
+		if len(args) != 1 {
+			return errNotCompiled
+		}
+		c.emit(opConst, c.constant(args[0]), 0)
+		if tail {
+			c.emit(opReturn, 0, 0)
+		}
+		return nil
+	case "quasiquote":
+		if len(args) != 1 {
+			return errNotCompiled
+		}
+		return c.compile(quasiquote(args[0]), tail)
+	case "progn":
+		return c.compileBody(args, tail)
+	case "if":
+		if len(args) != 2 && len(args) != 3 {
+			return errNotCompiled
+		}
+
+		if err := c.compile(args[0], false); err != nil {
+			return err
+		}
+		errJump := c.emit(opJumpIfError, 0, 0)
+		elseJump := c.emit(opJumpIfFalse, 0, 0)
+		if err := c.compile(args[1], tail); err != nil {
+			return err
+		}
+		endJump := c.emit(opJump, 0, 0)
+		c.patch(elseJump)
+		elseForm := atomNil
+		if len(args) == 3 {
+			elseForm = args[2]
+		}
+		if err := c.compile(elseForm, tail); err != nil {
+			return err
+		}
+		c.patch(endJump)
+		return c.finish(tail, errJump)
+	case "and", "or":
+		if len(args) == 0 {
+			return c.compile(atomBool(form == "and"), tail)
+		}
+
+		jumps := []int{}
+		for _, arg := range args[:len(args)-1] {
+			if err := c.compile(arg, false); err != nil {
+				return err
+			}
+			jumps = append(jumps,
+				c.emit(opJumpIfError, 0, 0),
+				c.emit(opJumpIfDecided, 0, fun.IF(form == "or", 1, 0)),
+			)
+		}
+		if err := c.compile(args[len(args)-1], tail); err != nil {
+			return err
+		}
+		return c.finish(tail, jumps...)
+	case "let":
+		if len(args) < 1 || args[0].Kind != AtomKindList || len(args[0].Value.(List))%2 != 0 {
+			return errNotCompiled
+		}
+
+		if c.top {
+			// names are bound in frame of top level form from now on
+			c.top = false
+			c.blocks = []map[Symbol]int{}
+			defer func() { c.top, c.blocks = true, nil }()
+		}
+		c.blocks = append(c.blocks, map[Symbol]int{})
+		defer func() { c.blocks = c.blocks[:len(c.blocks)-1] }()
+
+		bindings := args[0].Value.(List)
+		errJumps := []int{}
+		for i := 0; i < len(bindings); i += 2 {
+			if bindings[i].Kind != AtomKindSymbol {
+				return errNotCompiled
+			}
+
+			slot, err := c.bind(bindings[i].Value.(Symbol), bindings[i+1])
+			if err != nil {
+				return err
+			}
+			errJumps = append(errJumps, c.emit(opJumpIfError, 0, 0))
+			c.emit(opSetLocal, 0, slot)
+			c.emit(opPop, 0, 0)
+		}
+		if err := c.compileBody(args[1:], tail); err != nil {
+			return err
+		}
+		return c.finish(tail, errJumps...)
+	case "set":
+		if len(args) != 2 || args[0].Kind != AtomKindSymbol {
+			return errNotCompiled
+		}
+
+		slot := -1
+		if c.top {
+			if err := c.compile(args[1], false); err != nil {
+				return err
+			}
+		} else {
+			var err error
+			if slot, err = c.bind(args[0].Value.(Symbol), args[1]); err != nil {
+				return err
+			}
+		}
+		errJump := c.emit(opJumpIfError, 0, 0)
+		c.emit(opName, c.constant(args[0]), 0)
+		if slot == -1 {
+			c.emit(opSetGlobal, c.constant(args[0]), 0)
+		} else {
+			c.emit(opSetLocal, 0, slot)
+		}
+		c.patch(errJump)
+		if tail {
+			c.emit(opReturn, 0, 0)
+		}
+		return nil
+	case "fn":
+		return c.compileFn(l, tail)
+	default:
+		// forms changing shell scope or evaluating code at runtime
+		return errNotCompiled
+	}
+}
+
+// compileFn compiles lambda, making closure of it at runtime
+func (c *compiler) compileFn(l List, tail bool) error {
+	if len(l[1:]) < 2 || l[1].Kind != AtomKindList {
+		return errNotCompiled
+	}
+
+	fnc := &compiler{
+		proto:  &proto{},
+		parent: c,
+		env:    c.env,
+		blocks: []map[Symbol]int{{}},
+	}
+	params := l[1].Value.(List)
+	for i, param := range params {
+		if param.Kind != AtomKindSymbol {
+			return errNotCompiled
+		}
+
+		name := param.Value.(Symbol)
+		if name == "&key" {
+			return errNotCompiled
+		}
+		if name == "&" {
+			if i != len(params)-2 || params[i+1].Kind != AtomKindSymbol {
+				return errNotCompiled
+			}
+			fnc.proto.rest = true
+			fnc.declare(params[i+1].Value.(Symbol))
+			break
+		}
+		fnc.proto.params++
+		// repeated parameter is bound to the last argument, as in eval
+		fnc.blocks[0][name] = fnc.proto.slots
+		fnc.proto.slots++
+	}
+
+	meta := &Meta{
+		params: atomList(params...).String(),
+		pos:    sourcePos(c.env, l[len(l)-1]),
+	}
+	body := l[2:]
+	if len(body) > 1 && body[0].Kind == AtomKindString {
+		meta.doc, body = string(body[0].Value.(String)), body[1:]
+	}
+	fnc.proto.meta = meta
+
+	if err := fnc.compile(implicitProgn(body), true); err != nil {
+		return err
+	}
+
+	c.proto.protos = append(c.proto.protos, fnc.proto)
+	c.emit(opClosure, len(c.proto.protos)-1, 0)
+	if tail {
+		c.emit(opReturn, 0, 0)
+	}
+	return nil
+}
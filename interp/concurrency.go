@@ -0,0 +1,169 @@
+package interp
+
+import (
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// protect calls f, turning Go panic into lish error, as panic in goroutine
+// can not reach lish main loop
+func protect(f func() Atom) (res Atom) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case exitCode:
+			res = lisherr("exit %d called in goroutine", int(r))
+		default:
+			res = lisherr("internal error: %v", r)
+		}
+	}()
+	return f()
+}
+
+// spawn runs f in goroutine, returning future of its result
+func spawn(f func() Atom) Future {
+	future := Future{make(chan struct{}), new(Atom)}
+	go func() {
+		defer close(future.done)
+		*future.res = protect(f)
+	}()
+	return future
+}
+
+// builtinDeref waits for future to be done and returns its value
+func builtinDeref(env Env, args ...Atom) Atom {
+	future := args[0].Value.(Future)
+	select {
+	case <-future.done:
+		return *future.res
+	case <-env.scope.context().Done():
+		res, _ := env.scope.cancelled()
+		return res
+	}
+}
+
+func builtinChan(args ...Atom) Atom {
+	size := 0
+	if len(args) == 1 {
+		if size = int(args[0].Value.(Int)); size < 0 {
+			return lisherr("chan: buffer size must not be negative, but got %d", size)
+		}
+	}
+	return Atom{AtomKindStream, make(Stream, size)}
+}
+
+// builtinPut sends x to stream, waiting for receiver or buffer space
+func builtinPut(env Env, args ...Atom) (res Atom) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = lisherr("put!: stream is closed")
+		}
+	}()
+
+	select {
+	case args[0].Value.(Stream) <- args[1]:
+		return atomNil
+	case <-env.scope.context().Done():
+		res, _ := env.scope.cancelled()
+		return res
+	}
+}
+
+// builtinTake receives item from stream, nil once stream is closed and
+// drained
+func builtinTake(env Env, args ...Atom) Atom {
+	select {
+	case item, ok := <-args[0].Value.(Stream):
+		if !ok {
+			return atomNil
+		}
+		return item
+	case <-env.scope.context().Done():
+		res, _ := env.scope.cancelled()
+		return res
+	}
+}
+
+func builtinClose(args ...Atom) (res Atom) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = lisherr("close!: stream is already closed")
+		}
+	}()
+
+	close(args[0].Value.(Stream))
+	return atomNil
+}
+
+// builtinSelect waits for item from any of streams, returning list of item
+// and stream it came from. With :default nil is returned if no stream is
+// ready.
+func builtinSelect(env Env, args ...Atom) Atom {
+	cases := []reflect.SelectCase{}
+	sources := []Atom{} // stream of each case, nil for :default
+	for _, arg := range args {
+		switch {
+		case arg.Kind == AtomKindStream:
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(arg.Value.(Stream))})
+			sources = append(sources, arg)
+		case arg == atomKeyword("default"):
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+			sources = append(sources, atomNil)
+		default:
+			return lisherr("select: unknown option %s", arg)
+		}
+	}
+	if !slices.ContainsFunc(args, func(a Atom) bool { return a.Kind == AtomKindStream }) {
+		return lisherr("select: no streams to select from")
+	}
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(env.scope.context().Done())})
+	chosen, item, ok := reflect.Select(cases)
+	if chosen == len(sources) {
+		res, _ := env.scope.cancelled()
+		return res
+	}
+	stream := sources[chosen]
+	if cases[chosen].Dir == reflect.SelectDefault {
+		return atomNil
+	}
+	if !ok {
+		return atomList(atomNil, stream)
+	}
+	return atomList(item.Interface().(Atom), stream)
+}
+
+// builtinPmap calls f on each of xs in at most limit goroutines at once,
+// returning results in order of xs or first error
+func builtinPmap(env Env, args ...Atom) Atom {
+	f, xs := args[0], args[1].Value.(List)
+	limit := runtime.NumCPU()
+	if len(args) == 3 {
+		if limit = int(args[2].Value.(Int)); limit <= 0 {
+			return lisherr("pmap: limit must be positive, but got %d", limit)
+		}
+	}
+
+	res := make([]Atom, len(xs))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, x := range xs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			res[i] = protect(func() Atom { return call(f, []Atom{x}, env) })
+		}()
+	}
+	wg.Wait()
+
+	for _, a := range res {
+		if a.Kind == AtomKindError {
+			return a
+		}
+	}
+	return atomList(res...)
+}
@@ -0,0 +1,84 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrency(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"deref":          {`(deref (go (set x 1) (+ x 2)))`, atomInt(3)},
+		"await":          {`(await (go))`, atomNil},
+		"go_error":       {`(deref (go (throw "boom") 1))`, lisherr("boom")},
+		"go_exit":        {`(deref (go (exit 2)))`, lisherr("exit 2 called in goroutine")},
+		"chan_buffered":  {`(let (c (chan 2) _ (put! c 1) _ (put! c 2) a (take! c) b (take! c)) (list a b))`, atomList(atomInt(1), atomInt(2))},
+		"chan_closed":    {`(let (c (chan 1) _ (put! c 1) _ (close! c) a (take! c) b (take! c)) (list a b))`, atomList(atomInt(1), atomNil)},
+		"put_closed":     {`(let (c (chan) _ (close! c)) (put! c 1))`, lisherr("put!: stream is closed")},
+		"close_closed":   {`(let (c (chan) _ (close! c)) (close! c))`, lisherr("close!: stream is already closed")},
+		"chan_negative":  {`(chan -1)`, lisherr("chan: buffer size must not be negative, but got -1")},
+		"producer":       {`(let (c (chan) _ (go (put! c 1) (put! c 2) (close! c)) a (take! c) b (take! c) end (take! c)) (list a b end))`, atomList(atomInt(1), atomInt(2), atomNil)},
+		"select":         {`(let (a (chan 1) b (chan 1) _ (put! b 2) res (select a b)) (= res (list 2 b)))`, atomBool(true)},
+		"select_default": {`(select (chan) :default)`, atomNil},
+		"select_closed":  {`(let (c (chan) _ (close! c)) (= (select c) (list () c)))`, atomBool(true)},
+		"select_empty":   {`(select :default)`, lisherr("select: no streams to select from")},
+		"deref_timeout":  {`(let (c (chan) f (go (take! c)) res (with-timeout 50 (deref f)) _ (close! c)) res)`, lisherr("cancelled: timeout 50ms exceeded")},
+		"take_timeout":   {`(with-timeout 50 (take! (chan)))`, lisherr("cancelled: timeout 50ms exceeded")},
+		"put_timeout":    {`(with-timeout 50 (put! (chan) 1))`, lisherr("cancelled: timeout 50ms exceeded")},
+		"select_timeout": {`(with-timeout 50 (select (chan) (chan)))`, lisherr("cancelled: timeout 50ms exceeded")},
+		"pmap":           {`(pmap (fn (x) (* x x)) (list 1 2 3 4) 2)`, atomList(atomInt(1), atomInt(4), atomInt(9), atomInt(16))},
+		"pmap_error":     {`(pmap (fn (x) (/ 1 x)) (list 1 0))`, lisherr("division by zero")},
+		"go_timeout":     {`(deref (with-timeout 50 (go (let (f (fn (n) (f (+ n 1)))) (f 0)))))`, lisherr("cancelled: timeout 50ms exceeded")},
+		"pmap_limit":     {`(pmap + (list 1) 0)`, lisherr("pmap: limit must be positive, but got 0")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(read(tc.input), newEnvRepl()))
+		})
+	}
+}
+
+func TestConcurrentEnv(t *testing.T) {
+	repl_env := newEnvRepl()
+	eval(read(`(set counter (chan 100))`), repl_env)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			eval(read(fmt.Sprintf(`(set x%d %d)`, i, i)), repl_env)
+			eval(read(fmt.Sprintf(`(put! counter x%d)`, i)), repl_env)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, atomInt(99), eval(readValue(`x99`), repl_env))
+	eval(read(`(close! counter)`), repl_env)
+	assert.Len(t, eval(read(`(collect counter)`), repl_env).Value.(List), 100)
+}
+
+func TestFutureOutlivesEval(t *testing.T) {
+	in := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := in.Eval(ctx, `(set f (go (dotimes (i 10000) i) 42))`)
+	cancel()
+	assert.NoError(t, err)
+	res, err := in.Eval(context.Background(), `(deref f)`)
+	assert.NoError(t, err)
+	assert.Equal(t, atomInt(42), res)
+
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	_, err = in.Eval(ctx, `(set g (go (let (f (fn (n) (f (+ n 1)))) (f 0))))`)
+	assert.NoError(t, err)
+	cancelCause(errors.New("interrupted"))
+	res, _ = in.Eval(context.Background(), `(deref g)`)
+	assert.Equal(t, lisherr("cancelled: interrupted"), res)
+}
@@ -0,0 +1,340 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	atomType    = reflect.TypeFor[Atom]()
+	errorType   = reflect.TypeFor[error]()
+	contextType = reflect.TypeFor[context.Context]()
+)
+
+// ToAtom converts Go value to atom. Bools, numbers and strings become such
+// atoms, byte slices become strings, other slices and arrays become lists,
+// maps with string keys and structs become hashes, nil becomes nil. Atoms
+// are returned as is. Struct fields are named by lish tag or field name,
+// fields tagged "-" are skipped.
+//
+// Functions become builtins converting arguments with FromAtom. Function
+// might take context.Context first, which is cancelled along with evaluation.
+// Its results are converted with ToAtom, several results become list, last
+// error result becomes error atom if it is not nil.
+func ToAtom(v any) (Atom, error) {
+	return toAtom(reflect.ValueOf(v))
+}
+
+// FromAtom converts atom into value target points to, reversing ToAtom.
+// Ints are also converted to floats, keywords and symbols to strings. Atom is
+// converted to interface as Go value json would decode it into, or as atom
+// itself if it is function.
+func FromAtom(a Atom, target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("target must be non nil pointer, not %T", target)
+	}
+
+	v, err := fromAtom(a, ptr.Type().Elem())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(v)
+	return nil
+}
+
+// fieldName returns hash key of struct field, false if field is skipped
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	switch tag := field.Tag.Get("lish"); tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return tag, true
+	}
+}
+
+func toAtom(v reflect.Value) (Atom, error) {
+	if !v.IsValid() {
+		return atomNil, nil
+	}
+	if v.Type() == atomType {
+		return v.Interface().(Atom), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return atomBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return atomInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return Atom{}, fmt.Errorf("%d overflows int", v.Uint())
+		}
+		return atomInt(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return atomFloat(v.Float()), nil
+	case reflect.String:
+		return atomString(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return atomString(string(v.Bytes())), nil
+		}
+
+		res := make([]Atom, v.Len())
+		for i := range res {
+			item, err := toAtom(v.Index(i))
+			if err != nil {
+				return Atom{}, err
+			}
+			res[i] = item
+		}
+		return atomList(res...), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return Atom{}, fmt.Errorf("cannot convert %s to hash, keys must be strings", v.Type())
+		}
+
+		res := make(map[string]Atom, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			item, err := toAtom(it.Value())
+			if err != nil {
+				return Atom{}, err
+			}
+			res[it.Key().String()] = item
+		}
+		return atomHash(res), nil
+	case reflect.Struct:
+		res := map[string]Atom{}
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+
+			item, err := toAtom(v.Field(i))
+			if err != nil {
+				return Atom{}, err
+			}
+			res[name] = item
+		}
+		return atomHash(res), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return atomNil, nil
+		}
+		return toAtom(v.Elem())
+	case reflect.Func:
+		if v.IsNil() {
+			return atomNil, nil
+		}
+		return wrapFunc(v), nil
+	default:
+		return Atom{}, fmt.Errorf("cannot convert %s to atom", v.Type())
+	}
+}
+
+func fromAtom(a Atom, t reflect.Type) (reflect.Value, error) {
+	if t == atomType {
+		return reflect.ValueOf(a), nil
+	}
+
+	res := reflect.New(t).Elem()
+	mismatch := fmt.Errorf("cannot convert %s %s to %s", a.Kind, prStr(a), t)
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return res, mismatch
+		}
+
+		v, err := toGo(a)
+		if err != nil {
+			// functions are passed as is
+			v = a
+		}
+		if v != nil {
+			res.Set(reflect.ValueOf(v))
+		}
+	case reflect.Bool:
+		if a.Kind != AtomKindBool {
+			return res, mismatch
+		}
+		res.SetBool(bool(a.Value.(Bool)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Kind != AtomKindInt || res.OverflowInt(int64(a.Value.(Int))) {
+			return res, mismatch
+		}
+		res.SetInt(int64(a.Value.(Int)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Kind != AtomKindInt || a.Value.(Int) < 0 || res.OverflowUint(uint64(a.Value.(Int))) {
+			return res, mismatch
+		}
+		res.SetUint(uint64(a.Value.(Int)))
+	case reflect.Float32, reflect.Float64:
+		switch a.Kind {
+		case AtomKindInt:
+			res.SetFloat(float64(a.Value.(Int)))
+		case AtomKindFloat:
+			res.SetFloat(float64(a.Value.(Float)))
+		default:
+			return res, mismatch
+		}
+	case reflect.String:
+		switch a.Kind {
+		case AtomKindString:
+			res.SetString(string(a.Value.(String)))
+		case AtomKindKeyword:
+			res.SetString(string(a.Value.(Keyword)))
+		case AtomKindSymbol:
+			res.SetString(string(a.Value.(Symbol)))
+		default:
+			return res, mismatch
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && a.Kind == AtomKindString {
+			res.SetBytes([]byte(a.Value.(String)))
+			break
+		}
+		if a.Kind != AtomKindList {
+			return res, mismatch
+		}
+
+		l := a.Value.(List)
+		if l == nil {
+			break
+		}
+		res.Set(reflect.MakeSlice(t, len(l), len(l)))
+		for i, item := range l {
+			v, err := fromAtom(item, t.Elem())
+			if err != nil {
+				return res, err
+			}
+			res.Index(i).Set(v)
+		}
+	case reflect.Map:
+		if a.Kind != AtomKindHash || t.Key().Kind() != reflect.String {
+			return res, mismatch
+		}
+
+		res.Set(reflect.MakeMapWithSize(t, len(a.Value.(Hash))))
+		for k, item := range a.Value.(Hash) {
+			v, err := fromAtom(item, t.Elem())
+			if err != nil {
+				return res, err
+			}
+			res.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), v)
+		}
+	case reflect.Struct:
+		if a.Kind != AtomKindHash {
+			return res, mismatch
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			item, ok := a.Value.(Hash)[name]
+			if !ok {
+				continue
+			}
+
+			v, err := fromAtom(item, t.Field(i).Type)
+			if err != nil {
+				return res, fmt.Errorf("field %s: %w", name, err)
+			}
+			res.Field(i).Set(v)
+		}
+	case reflect.Pointer:
+		if a.Kind == AtomKindList && len(a.Value.(List)) == 0 {
+			// nil is nil pointer
+			break
+		}
+
+		v, err := fromAtom(a, t.Elem())
+		if err != nil {
+			return res, err
+		}
+		res.Set(reflect.New(t.Elem()))
+		res.Elem().Set(v)
+	default:
+		return res, mismatch
+	}
+	return res, nil
+}
+
+// wrapFunc makes builtin calling Go function fn, see ToAtom
+func wrapFunc(fn reflect.Value) Atom {
+	t := fn.Type()
+	first := 0
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		first = 1
+	}
+	returnsErr := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+
+	params, names := []param{}, []string{}
+	for i := first; i < t.NumIn(); i++ {
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			params = append(params, variadic())
+			names = append(names, "&", t.In(i).Elem().String())
+			break
+		}
+		params = append(params, arg())
+		names = append(names, t.In(i).String())
+	}
+	// parameter type of i-th argument
+	paramType := func(i int) reflect.Type {
+		if t.IsVariadic() && first+i >= t.NumIn()-1 {
+			return t.In(t.NumIn() - 1).Elem()
+		}
+		return t.In(first + i)
+	}
+
+	res := atomFuncEnv(func(env Env, args ...Atom) Atom {
+		in := make([]reflect.Value, 0, first+len(args))
+		if first == 1 {
+			in = append(in, reflect.ValueOf(env.scope.context()))
+		}
+		for i, a := range args {
+			v, err := fromAtom(a, paramType(i))
+			if err != nil {
+				return lisherr("Expected %d-th argument to be %s: %s", i, paramType(i), err)
+			}
+			in = append(in, v)
+		}
+
+		out := fn.Call(in)
+		if returnsErr {
+			if err := out[len(out)-1]; !err.IsNil() {
+				return lisherr("%s", err.Interface().(error))
+			}
+			out = out[:len(out)-1]
+		}
+
+		results := make([]Atom, len(out))
+		for i, v := range out {
+			a, err := toAtom(v)
+			if err != nil {
+				return lisherr("%s", err)
+			}
+			results[i] = a
+		}
+		switch len(results) {
+		case 0:
+			return atomNil
+		case 1:
+			return results[0]
+		default:
+			return atomList(results...)
+		}
+	}, signature(params...))
+	res.Value.(Func).meta.params = "(" + strings.Join(names, " ") + ")"
+	return res
+}
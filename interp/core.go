@@ -0,0 +1,687 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+// exitCode is panicked by exit builtin to stop lish with the code
+type exitCode int
+
+func int_bin_op(init Int, op func(Int, Int) Int) Atom {
+	return atomFunc(func(args ...Atom) Atom {
+		res := init
+		for _, arg := range args {
+			res = op(res, arg.Value.(Int))
+		}
+		return Atom{AtomKindInt, res}
+	}, signature(variadic(AtomKindInt)))
+}
+
+func logical_op(op func(a, b Atom) bool) Atom {
+	return atomFunc(func(args ...Atom) Atom {
+		res := true
+		x := args[0]
+		for _, arg := range args[1:] {
+			res = res && op(x, arg)
+		}
+		return atomBool(res)
+	}, signature(arg(), variadic()))
+}
+
+var namespace = map[Symbol]Atom{
+	// ARITHMETIC
+	"+": documented("(& ints)", "Returns sum of ints.", int_bin_op(0, func(a, b Int) Int { return a + b })),
+	"*": documented("(& ints)", "Returns product of ints.", int_bin_op(1, func(a, b Int) Int { return a * b })),
+	"/": documented("(int & ints)", "Divides int by each of ints in turn.", atomFunc(func(args ...Atom) Atom {
+		res := args[0].Value.(Int)
+		for _, arg := range args[1:] {
+			if arg.Value.(Int) == 0 {
+				return lisherr("division by zero")
+			}
+			res /= arg.Value.(Int)
+		}
+		return Atom{AtomKindInt, res}
+	}, signature(arg(AtomKindInt), variadic(AtomKindInt)))),
+	"-": documented("(int & ints)", "Subtracts ints from int, negates int if there are no ints.", atomFunc(func(args ...Atom) Atom {
+		if len(args) == 1 {
+			return Atom{AtomKindInt, -args[0].Value.(Int)}
+		}
+
+		res := args[0].Value.(Int)
+		for _, b := range args[1:] {
+			res -= b.Value.(Int)
+		}
+		return Atom{AtomKindInt, res}
+	}, signature(arg(AtomKindInt), variadic(AtomKindInt)))),
+	// LOGIC
+	"ok?": documented("(x)", "Returns whether x is truthy: everything but false and result of failed command is.", atomFunc(func(args ...Atom) Atom {
+		return atomBool(truthy(args[0]))
+	}, signature(arg()))),
+	"cancelled?": documented("(x)", "Returns whether x is error of evaluation cancelled by timeout or interrupt.", atomFunc(func(args ...Atom) Atom {
+		return atomBool(isCancelled(args[0]))
+	}, signature(arg()))),
+	"not": documented("(x)", "Returns whether x is falsy.", atomFunc(func(args ...Atom) Atom {
+		return atomBool(!truthy(args[0]))
+	}, signature(arg()))),
+	// COMPARISON
+	"=":  documented("(x & xs)", "Returns whether x is equal to each of xs.", logical_op(atomEq)),
+	"<":  documented("(x & xs)", "Returns whether x is less than each of xs.", logical_op(func(a, b Atom) bool { return atomCmp(a, b) < 0 })),
+	"<=": documented("(x & xs)", "Returns whether x is less than or equal to each of xs.", logical_op(func(a, b Atom) bool { return atomCmp(a, b) <= 0 })),
+	">":  documented("(x & xs)", "Returns whether x is greater than each of xs.", logical_op(func(a, b Atom) bool { return atomCmp(a, b) > 0 })),
+	">=": documented("(x & xs)", "Returns whether x is greater than or equal to each of xs.", logical_op(func(a, b Atom) bool { return atomCmp(a, b) >= 0 })),
+	"hash": documented("(x)", "Returns int hash of x, equal values have equal hashes. Hash keys are still only strings and keywords.", atomFunc(func(args ...Atom) Atom {
+		return atomInt(hashOf(args[0]))
+	}, signature(arg()))),
+	// PRINTING
+	"dbg": documented("(& xs)", "Prints debug representation of xs.", atomFuncNil(func(args ...Atom) {
+		fmt.Println(strings.Join(fun.Map[string](func(a Atom) string {
+			return fmt.Sprintf("%#v", a)
+		}, args...), " "))
+	}, signature(variadic()))),
+	"print": documented("(& xs)", "Prints xs separated by spaces.", atomFuncNil(func(args ...Atom) {
+		fmt.Print(strings.Join(fun.Map[string](Atom.String, args...), " "))
+	}, signature(variadic()))),
+	"println": documented("(& xs)", "Prints xs separated by spaces and newline.", atomFuncNil(func(args ...Atom) {
+		fmt.Println(strings.Join(fun.Map[string](Atom.String, args...), " "))
+	}, signature(variadic()))),
+	"echo": documented("(& xs)", "Returns string of xs separated by spaces.", atomFunc(func(args ...Atom) Atom {
+		return atomString(strings.Join(fun.Map[string](Atom.String, args...), " "))
+	}, signature(variadic()))),
+	"pprint": documented("(x [width])", "Prints x readably, laid out to fit in width, terminal width by default.", atomFunc(func(args ...Atom) Atom {
+		width := screenWidth()
+		if len(args) == 2 {
+			width = int(args[1].Value.(Int))
+		}
+		fmt.Println(pprint(args[0], width))
+		return atomNil
+	}, signature(arg(), optional(AtomKindInt)))),
+	"pr-str": documented("(& xs)", "Returns readable representation of xs separated by spaces, which read turns back to xs.", atomFunc(func(args ...Atom) Atom {
+		return atomString(strings.Join(fun.Map[string](prStr, args...), " "))
+	}, signature(variadic()))),
+	"str": documented("(& xs)", "Returns concatenation of xs, strings are not quoted.", atomFunc(func(args ...Atom) Atom {
+		return atomString(strings.Join(fun.Map[string](Atom.String, args...), ""))
+	}, signature(variadic()))),
+	// LIST MANIPULATION
+	"cons": documented("(& xs list)", "Returns list with xs prepended to list.", atomFunc(func(args ...Atom) Atom {
+		elems := args[:len(args)-1]
+		switch v := args[len(args)-1]; v.Kind {
+		case AtomKindList:
+			v := v.Value.(List)
+			if len(v) == 0 {
+				return atomList(elems...)
+			}
+			return atomList(append(elems, v...)...)
+		default:
+			return lisherr("Trying to cons not a list")
+		}
+	}, signature(arg(), variadic()))),
+	"first": documented("(list)", "Returns first element of list, nil if list is empty.", atomFunc(func(args ...Atom) Atom {
+		// first of nil is nil
+		if list := args[0].Value.(List); len(list) > 0 {
+			return list[0]
+		}
+		return atomNil
+	}, signature(arg(AtomKindList)))),
+	"rest": documented("(list)", "Returns list without its first element.", atomFunc(func(args ...Atom) Atom {
+		if list := args[0].Value.(List); len(list) > 0 {
+			return atomList(list[1:]...)
+		}
+		return atomNil
+	}, signature(arg(AtomKindList)))),
+	"list": documented("(& xs)", "Returns list of xs.", atomFunc(atomList, signature(variadic()))),
+	"empty?": documented("(list)", "Returns whether list is empty.", atomFunc(func(args ...Atom) Atom {
+		if args[0].Kind == AtomKindList {
+			return atomBool(len(args[0].Value.(List)) == 0)
+		}
+		return lisherr("Trying to get empty? of %s", strings.Join(fun.Map[string](Atom.String, args...), " "))
+	}, signature(arg()))),
+	"len": documented("(list)", "Returns number of elements in list.", atomFunc(func(args ...Atom) Atom {
+		if args[0].Kind == AtomKindList {
+			return atomInt(len(args[0].Value.(List)))
+		}
+		return lisherr("Trying to get len of %s", strings.Join(fun.Map[string](Atom.String, args...), " "))
+	}, signature(arg()))),
+	"list?": documented("(x)", "Returns whether x is list.", atomFunc(func(args ...Atom) Atom {
+		return atomBool(args[0].Kind == AtomKindList)
+	}, signature(arg()))),
+	"concat": documented("(& lists)", "Returns concatenation of lists.", atomFunc(func(args ...Atom) Atom {
+		return atomList(fun.ConcatMap(func(arg Atom) []Atom {
+			// TODO: support nil
+			return arg.Value.(List)
+		}, args...)...)
+	}, signature(variadic(AtomKindList)))),
+	// OTHER
+	"apply": documented("(f & args)", "Calls f with args.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		return call(args[0], args[1:], env)
+	}, signature(arg(AtomKindFunc, AtomKindLambda), variadic()))),
+	"read": documented("(string)", "Reads form from string.", atomFunc(func(args ...Atom) Atom {
+		return readValue(string(args[0].Value.(String)))
+	}, signature(arg(AtomKindString)))),
+	"slurp": documented("(path)", "Returns content of file.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		filename, err := env.scope.readPath(string(args[0].Value.(String)))
+		if err != nil {
+			return lisherr("%s", err)
+		}
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return lisherr(err.Error())
+		}
+		return atomString(string(b))
+	}, signature(arg(AtomKindString)))),
+	"join": documented("(& xs)", "Returns concatenation of xs, strings are not quoted.", atomFunc(func(args ...Atom) Atom {
+		return atomString(strings.Join(fun.Map[string](func(a Atom) string {
+			if a.Kind == AtomKindString {
+				return string(a.Value.(String))
+			}
+			return a.String()
+		}, args...), ""))
+	}, signature(variadic()))),
+	"throw": documented("(message)", "Returns error with message.", atomFunc(func(args ...Atom) Atom {
+		return lisherr(args[0].String())
+	}, signature(arg()))),
+	"exit": documented("([code])", "Exits lish with code, 0 by default.", atomFunc(func(args ...Atom) Atom {
+		code := 0
+		if len(args) == 1 {
+			code = int(args[0].Value.(Int))
+		}
+		panic(exitCode(code))
+	}, signature(optional(AtomKindInt)))),
+	// ENVIRONMENT VARIABLES
+	"env": documented("([name])", "Returns value of environment variable, or hash of all variables if name is omitted.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		if len(args) == 1 {
+			value, ok := env.scope.getenv(string(args[0].Value.(String)))
+			if !ok {
+				return atomNil
+			}
+			return atomString(value)
+		}
+
+		res := map[string]Atom{}
+		for _, kv := range env.scope.environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			res[k] = atomString(v)
+		}
+		return atomHash(res)
+	}, signature(optional(AtomKindString)))),
+	"setenv": documented("(name value)", "Sets environment variable of lish process.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		if err := env.scope.environment(); err != nil {
+			return lisherr("setenv: %s", err.Error())
+		}
+		if err := os.Setenv(string(args[0].Value.(String)), string(args[1].Value.(String))); err != nil {
+			return lisherr("%s", err)
+		}
+		return args[1]
+	}, signature(arg(AtomKindString), arg(AtomKindString)))),
+	"unsetenv": documented("(name)", "Removes environment variable of lish process.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		if err := env.scope.environment(); err != nil {
+			return lisherr("unsetenv: %s", err.Error())
+		}
+		os.Unsetenv(string(args[0].Value.(String)))
+		return atomNil
+	}, signature(arg(AtomKindString)))),
+	// COMMANDS
+	"which": documented("(name)", "Returns path of executable found in PATH, nil if there is none.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		path, ok := commands.lookup(env.scope, string(args[0].Value.(String)))
+		if !ok {
+			return atomNil
+		}
+		return atomString(path)
+	}, signature(arg(AtomKindString)))),
+	"type": documented("(name)", "Tells whether name is special form, builtin, lambda, macro or executable.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		var name string
+		switch arg := args[0]; arg.Kind {
+		case AtomKindSymbol:
+			name = string(arg.Value.(Symbol))
+		case AtomKindString:
+			name = string(arg.Value.(String))
+		default:
+			return atomString(describeAtom(arg))
+		}
+
+		res, ok := describe(env, name)
+		if !ok {
+			return lisherr("%s", commandNotFound(env, name))
+		}
+		return atomString(res)
+	}, signature(arg()))),
+	"file": documented("(path & :append)", "Returns redirection of stream into file for with-io.", atomFuncEnv(builtinFile, signature(arg(AtomKindString), variadic(AtomKindKeyword)))),
+	"rehash": documented("()", "Forgets executables found in PATH.", atomFuncNil(func(...Atom) {
+		commands.rehash()
+	}, signature())),
+	// WORKING DIRECTORY
+	"cd":    documented("([dir])", "Changes current directory to dir, home if dir is omitted, previous one if dir is -.", atomFuncEnv(builtinCd, signature(optional(AtomKindString)))),
+	"pwd":   documented("()", "Returns current directory.", atomFuncEnv(builtinPwd, signature())),
+	"dirs":  documented("()", "Returns current directory followed by directory stack.", atomFuncEnv(builtinDirs, signature())),
+	"pushd": documented("(dir)", "Pushes current directory to directory stack and changes it to dir.", atomFuncEnv(builtinPushd, signature(arg(AtomKindString)))),
+	"popd":  documented("()", "Changes current directory to one popped from directory stack.", atomFuncEnv(builtinPopd, signature())),
+	// FILES
+	"ls":          documented("([path])", "Returns hashes with name, size, mode, mtime and is-dir of entries of directory, current one by default.", atomFuncEnv(builtinLs, signature(optional(AtomKindString)))),
+	"stat":        documented("(path)", "Returns hash with name, size, mode, mtime and is-dir of file.", atomFuncEnv(builtinStat, signature(arg(AtomKindString)))),
+	"exists?":     documented("(path)", "Returns whether file exists.", atomFuncEnv(builtinExists, signature(arg(AtomKindString)))),
+	"mkdir":       documented("(& dirs)", "Makes dirs along with missing parents.", atomFuncEnv(builtinMkdir, signature(variadic(AtomKindString)))),
+	"rm":          documented("(& paths :recursive)", "Removes files and empty directories, or directories with contents if :recursive is given.", atomFuncEnv(builtinRm, signature(variadic(AtomKindString, AtomKindKeyword)))),
+	"mv":          documented("(src dst)", "Moves src to dst, or into dst if it is directory.", atomFuncEnv(builtinMv, signature(arg(AtomKindString), arg(AtomKindString)))),
+	"cp":          documented("(src dst)", "Copies file or directory src to dst, or into dst if it is directory.", atomFuncEnv(builtinCp, signature(arg(AtomKindString), arg(AtomKindString)))),
+	"spit":        documented("(path x)", "Writes x to file, replacing its content.", atomFuncEnv(builtinSpit, signature(arg(AtomKindString), arg()))),
+	"append-file": documented("(path x)", "Appends x to file.", atomFuncEnv(builtinAppendFile, signature(arg(AtomKindString), arg()))),
+	"temp-file":   documented("([pattern])", "Creates empty temporary file named by pattern, last * in it is replaced with random string. Returns its path.", atomFuncEnv(builtinTempFile, signature(optional(AtomKindString)))),
+	"temp-dir":    documented("([pattern])", "Creates temporary directory named by pattern, last * in it is replaced with random string. Returns its path.", atomFuncEnv(builtinTempDir, signature(optional(AtomKindString)))),
+	"read-lines":  documented("(path)", "Returns stream of lines of file, read as stream is consumed. Stream is closed once evaluation is over.", atomFuncEnv(builtinReadLines, signature(arg(AtomKindString)))),
+	"collect":     documented("(stream)", "Returns list of all remaining items of stream.", atomFunc(builtinCollect, signature(arg(AtomKindStream)))),
+	// CONCURRENCY
+	"deref":  documented("(future)", "Waits for future started by go and returns its value.", atomFuncEnv(builtinDeref, signature(arg(AtomKindFuture)))),
+	"await":  documented("(future)", "Same as deref.", atomFuncEnv(builtinDeref, signature(arg(AtomKindFuture)))),
+	"chan":   documented("([size])", "Returns stream with buffer for size items, unbuffered by default.", atomFunc(builtinChan, signature(optional(AtomKindInt)))),
+	"put!":   documented("(stream x)", "Sends x to stream, waiting for receiver or buffer space.", atomFuncEnv(builtinPut, signature(arg(AtomKindStream), arg()))),
+	"take!":  documented("(stream)", "Receives item from stream, nil once stream is closed and drained.", atomFuncEnv(builtinTake, signature(arg(AtomKindStream)))),
+	"close!": documented("(stream)", "Closes stream, so receivers get nil once it is drained.", atomFunc(builtinClose, signature(arg(AtomKindStream)))),
+	"select": documented("(& streams :default)", "Waits for item from any of streams, returns list of item and stream it came from. Returns nil if no stream is ready and :default is given.", atomFuncEnv(builtinSelect, signature(variadic(AtomKindStream, AtomKindKeyword)))),
+	"pmap":   documented("(f xs [limit])", "Returns list of f called on each of xs in parallel, at most limit calls at once, number of CPUs by default.", atomFuncEnv(builtinPmap, signature(arg(AtomKindFunc, AtomKindLambda), arg(AtomKindList), optional(AtomKindInt)))),
+	// GLOBBING
+	"glob": documented("(& patterns)", "Returns sorted paths matching patterns, which might contain * ? [abc] {a,b} and **.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		if err := env.scope.files(); err != nil {
+			return lisherr("glob: %s", err.Error())
+		}
+
+		dir, err := env.scope.getwd()
+		if err != nil {
+			return lisherr("%s", err)
+		}
+
+		res := []Atom{}
+		for _, pattern := range args {
+			matches, err := glob(dir, string(pattern.Value.(String)))
+			if err != nil {
+				return lisherr("glob: %s", err.Error())
+			}
+			res = append(res, fun.Map[Atom](atomString[string], matches...)...)
+		}
+		return atomList(res...)
+	}, signature(variadic(AtomKindString)))),
+	// ENCODING
+	"json/parse":     documented("(x)", "Decodes json string, or stdout of successful command, objects become hashes and arrays become lists.", atomFunc(builtinJSONParse, signature(arg(AtomKindString, AtomKindHash)))),
+	"json/stringify": documented("(x & :pretty)", "Encodes x as json, indented if :pretty is given. Hash keys are sorted.", atomFunc(builtinJSONStringify, signature(arg(), variadic(AtomKindKeyword)))),
+	"yaml/parse":     documented("(x)", "Decodes yaml string, or stdout of successful command, mappings become hashes and sequences become lists.", atomFunc(builtinYAMLParse, signature(arg(AtomKindString, AtomKindHash)))),
+	"yaml/stringify": documented("(x)", "Encodes x as yaml. Hash keys are sorted.", atomFunc(builtinYAMLStringify, signature(arg()))),
+	"toml/parse":     documented("(x)", "Decodes toml string, or stdout of successful command, into hash.", atomFunc(builtinTOMLParse, signature(arg(AtomKindString, AtomKindHash)))),
+	"toml/stringify": documented("(hash)", "Encodes hash as toml. Keys are sorted.", atomFunc(builtinTOMLStringify, signature(arg(AtomKindHash)))),
+	"csv/parse":      documented("(x & :header :tsv)", "Decodes csv string, or stdout of successful command, into list of records, which are hashes keyed by first record if :header is given. Values are tab separated if :tsv is given.", atomFunc(builtinCSVParse, signature(arg(AtomKindString, AtomKindHash), variadic(AtomKindKeyword)))),
+	"csv/write":      documented("(rows & :tsv)", "Encodes rows, which are lists or hashes, as csv. Rows of hashes are preceded by header of their keys.", atomFunc(builtinCSVWrite, signature(arg(AtomKindList), variadic(AtomKindKeyword)))),
+	"parse-table":    documented("(x)", "Parses header and whitespace aligned columns, like output of ps or df, into list of hashes keyed by column names.", atomFunc(builtinParseTable, signature(arg(AtomKindString, AtomKindHash)))),
+	// RECORDS
+	"kind": documented("(x)", "Returns name of kind of x, which is name of record kind for records.", atomFunc(func(args ...Atom) Atom {
+		return atomString(kindName(args[0]))
+	}, signature(arg()))),
+	"satisfies?": documented("(protocol x)", "Returns whether all methods of protocol are implemented for kind of x.", atomFuncEnv(func(env Env, args ...Atom) Atom {
+		if args[0].Value.(Record).typ != protocolType {
+			return lisherr("%s is not a protocol", args[0])
+		}
+		return atomBool(satisfies(env, args[0].Value.(Record), args[1]))
+	}, signature(arg(AtomKindRecord), arg()))),
+	// DOCUMENTATION
+	"doc":     documented("(x)", "Returns documentation of function or special form.", atomFuncEnv(builtinDoc, signature(arg()))),
+	"apropos": documented("(pattern)", "Returns names of functions and special forms whose name or doc contains pattern.", atomFuncEnv(builtinApropos, signature(arg(AtomKindString)))),
+	"source":  documented("(f)", "Returns source of lambda, or where builtin is defined.", atomFunc(builtinSource, signature(arg(AtomKindFunc, AtomKindLambda)))),
+}
+
+func init() {
+	// registered here since they refer to namespace themselves
+	namespace["load-file"] = documented("(path)", "Evaluates file in root environment.", atomFuncEnv(builtinLoadFile, signature(arg(AtomKindString))))
+	namespace["require"] = documented("(name & :as alias)", "Evaluates module once, its exports are accessed as name/export or alias/export. Returns hash of exports.", atomFuncEnv(builtinRequire, signature(arg(AtomKindString, AtomKindSymbol), variadic())))
+
+	for name, fn := range namespace {
+		namespace[name] = named(fn, name)
+	}
+	applyMeta = namespace["apply"].Value.(Func).meta
+}
+
+// mod core_tests {
+//     use crate::{
+//         lisherr,
+//         args,
+//         form,
+//         types::Atom,
+//     };
+//     use super::namespace;
+
+//     fn get_fn(name: &str) -> fn(Vec<Atom>) -> Atom {
+//         let ns = namespace();
+//         match ns.get(name) {
+//             Some(Atom::Func(f)) => f.clone(),
+//             _ => unreachable!(),
+//         }
+//     }
+
+//     macro_rules! test_function {
+//         ($test_name:ident, $($fun:expr, $args:expr => $res:expr),* $(,)?) => {
+//             #[test]
+//             fn $test_name() {
+//                 let ns = namespace();
+//                 $(
+//                     assert_eq!(match ns.get($fun) {
+//                         Some(Atom::Func(f)) => f($args),
+//                         Some(_) => lisherr!("{:?} is not a function", $fun),
+//                         None => lisherr!("{:?} was not found", $fun),
+//                     }, Atom::from($res));
+//                 )*
+//             }
+//         }
+//     }
+
+//     // (*)
+//     test_function!(
+//         multiply_nullary,
+//         "*", args![] => 1,
+//     );
+
+//     // (* 2)
+//     test_function!(
+//         multiply_unary,
+//         "*", args![2] => 2,
+//     );
+
+//     // (* 1 2 3)
+//     test_function!(
+//         multiply_ternary,
+//         "*", args![1, 2, 3] => 6,
+//     );
+
+//     // (/ 1)
+//     test_function!(
+//         divide_unary,
+//         "/", args![1] => 1,
+//     );
+
+//     // (/ 5 2)
+//     test_function!(
+//         divide_binary,
+//         "/", args![5, 2] => 2,
+//     );
+
+//     // (/ 22 3 2)
+//     test_function!(
+//         divide_ternary,
+//         "/", args![22, 3, 2] => 3,
+//     );
+
+//     // (- 1)
+//     test_function!(
+//         minus_unary,
+//         "-", args![1] => (-1),
+//     );
+
+//     // (- 1 2 3)
+//     test_function!(
+//         minus_ternary,
+//         "-", args![1, 2, 3] => (-4),
+//     );
+
+//     // (+)
+//     test_function!(
+//         plus_nullary,
+//         "+", args![] => 0,
+//     );
+
+//     // (+ 1)
+//     test_function!(
+//         plus_unary,
+//         "+", args![1] => 1,
+//     );
+
+//     // (+ 1 2 3)
+//     test_function!(
+//         plus_ternary,
+//         "+", args![1, 2, 3] => 6
+//     );
+
+//     test_function!(
+//         not_equal_ints,
+//         "=", args![1, 2] => false
+//     );
+
+//     test_function!(
+//         equal_ints,
+//         "=", args![2, 2] => true
+//     );
+
+//     test_function!(
+//         less_true,
+//         "<", args![1, 2] => true
+//     );
+
+//     test_function!(
+//         less_false,
+//         "<", args![2, 1] => false
+//     );
+
+//     test_function!(
+//         less_equal_true,
+//         "<=", args![1, 1] => true
+//     );
+
+//     test_function!(
+//         less_equal_false,
+//         "<=", args![2, 1] => false
+//     );
+
+//     test_function!(
+//         greater_true,
+//         ">", args![2, 1] => true
+//     );
+
+//     test_function!(
+//         greater_false,
+//         ">", args![1, 2] => false
+//     );
+
+//     test_function!(
+//         greater_equal_true,
+//         ">=", args![2, 2] => true
+//     );
+
+//     test_function!(
+//         greater_equal_false,
+//         ">=", args![1, 2] => false
+//     );
+
+//     /* TODO: rewrite to using write!
+//     test_function!(
+//         print_int,
+//         "print", args![92] => "92"
+//     );
+
+//     test_function!(
+//         print_ints,
+//         "print", args![1, 2, 3] => "1 2 3"
+//     );
+
+//     test_function!(
+//         print_strs,
+//         "print", args!["a", "b", "c"] => "a b c"
+//     );
+
+//     test_function!(
+//         print_multiline_str,
+//         "print", args!["a\nc"] => "a\\nc"
+//     );
+//     */
+
+//     test_function!(
+//         echo_int,
+//         "echo", args![1] => "1"
+//     );
+
+//     test_function!(
+//         echo_strs,
+//         "echo", args!["a", "b", "c"] => "a b c"
+//     );
+
+//     test_function!(
+//         echo_multiline_str,
+//         "echo", args!["a\nc"] => "a\nc"
+//     );
+
+//     #[test]
+//     fn apply_plus() {
+//         let ns = namespace();
+//         assert_eq!(
+//             get_fn("apply")(args![ns.get("+").unwrap().clone(), 1, 2, 3]),
+//             Atom::from(6)
+//         )
+//     }
+
+//     #[test]
+//     fn apply_lambda() {
+//         use std::rc::Rc;
+//         use crate::{eval, env::Env};
+//         let lambda = Atom::Lambda {
+//             eval,
+//             params: vec!["&".to_owned(), "x".to_owned()],
+//             ast: Rc::new(Atom::symbol("x")),
+//             env: Env::new(None),
+//             is_macro: false,
+//             // meta: Rc::new(Atom::Nil),
+//         };
+//         assert_eq!(
+//             get_fn("apply")(args![lambda, 1, 2, 3]),
+//             form![1, 2, 3]
+//         );
+//     }
+
+//     #[test]
+//     fn apply_int_not_a_function() {
+//         assert_eq!(
+//             get_fn("apply")(args![1, 2, 3]),
+//             lisherr!("1 is not a function")
+//         )
+//     }
+
+//     #[test]
+//     fn cons_int_not_a_list() {
+//         assert_eq!(
+//             get_fn("cons")(args![1, 2]),
+//             lisherr!("Trying to cons not a list")
+//         )
+//     }
+
+//     test_function!(
+//         cons_int,
+//         "cons", args![1, form![]] => form![1]
+//     );
+
+//     test_function!(
+//         concat_int_lists,
+//         "concat", args![
+//             form![],
+//             form![1],
+//             form![2, 3],
+//             form![4, 5, 6, 7]
+//         ] => form![1, 2, 3, 4, 5, 6, 7]
+//     );
+
+//     test_function!(
+//         concat_int_and_str_lists,
+//         "concat", args![
+//             form![1, 2, 3],
+//             form!["a", "b", "c"]
+//         ] => form![1, 2, 3, "a", "b", "c"]
+//     );
+
+//     test_function!(
+//         list_ints,
+//         "list", args![1, 2, 3] => form![1, 2, 3]
+//     );
+
+//     test_function!(
+//         first_ints,
+//         "first", args![form![1, 2, 3]] => 1
+//     );
+
+//     #[test]
+//     fn first_int_not_a_list() {
+//         assert_eq!(
+//             get_fn("first")(args![1]),
+//             lisherr!("Trying to get first of not list")
+//         )
+//     }
+
+//     test_function!(
+//         rest_ints,
+//         "rest", args![form![1, 2, 3]] => args![2, 3]
+//     );
+
+//     #[test]
+//     fn rest_int_not_a_list() {
+//         assert_eq!(
+//             get_fn("rest")(args![1]),
+//             lisherr!("Trying to get rest of not list")
+//         )
+//     }
+
+//     test_function!(
+//         len_ints,
+//         "len", args![form![1, 2, 3]] => 3
+//     );
+
+//     #[test]
+//     fn len_int_not_a_list() {
+//         assert_eq!(
+//             get_fn("len")(args![1]),
+//             lisherr!("Trying to get len of not list")
+//         )
+//     }
+
+//     test_function!(
+//         islist_nil,
+//         "list?", args![form![]] => true
+//     );
+
+//     test_function!(
+//         islist_int,
+//         "list?", args![1] => false
+//     );
+
+//     test_function!(
+//         islist_ints,
+//         "list?", args![form![1, 2, 3]] => true
+//     );
+
+//     test_function!(
+//         isempty_nil,
+//         "empty?", args![form![]] => true
+//     );
+
+//     test_function!(
+//         isempty_ints,
+//         "empty?", args![form![1, 2, 3]] => false
+//     );
+
+//     test_function!(
+//         read_str,
+//         "read", args!["(+ 1 2)"] => form![Atom::symbol("+"), 1, 2]
+//     );
+
+//     #[test]
+//     fn read_int() {
+//         assert_eq!(
+//             get_fn("read")(args![1]),
+//             lisherr!("1 is not a string")
+//         )
+//     }
+
+//     test_function!(
+//         str_ints,
+//         "join", args![1, 2, 3] => "123"
+//     );
+
+//     test_function!(
+//         str_newline_str,
+//         "join", args!["a\nc"] => "a\nc"
+//     );
+
+//     // TODO: test slurp
+// }
@@ -0,0 +1,182 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rprtr258/fun"
+)
+
+// positions maps bodies of function forms read to lines they start at. Body
+// list is identified by its first element, as macros like defun pass body
+// through unchanged.
+var positions = struct {
+	mu    sync.Mutex
+	lines map[*Atom]int
+}{lines: map[*Atom]int{}}
+
+// rememberPosition records line of function form read into frame
+func rememberPosition(f Frame) {
+	if f.isHash || f.a.Kind != AtomKindList {
+		return
+	}
+
+	l := f.a.Value.(List)
+	if len(l) < 3 || !slices.Contains([]Atom{atomSymbol("fn"), atomSymbol("defun"), atomSymbol("defmacro")}, l[0]) {
+		return
+	}
+
+	body := l[len(l)-1]
+	if body.Kind != AtomKindList || len(body.Value.(List)) == 0 {
+		return
+	}
+
+	positions.mu.Lock()
+	defer positions.mu.Unlock()
+	positions.lines[&body.Value.(List)[0]] = f.line
+}
+
+// sourcePos returns where function with body is defined: file being loaded
+// and line body starts at, if they are known
+func sourcePos(env Env, body Atom) string {
+	pos := ""
+	if file, ok := env.get("*FILE*"); ok && file.Kind == AtomKindString {
+		pos = string(file.Value.(String))
+	}
+
+	if body.Kind != AtomKindList || len(body.Value.(List)) == 0 || pos == "" {
+		return pos
+	}
+
+	positions.mu.Lock()
+	defer positions.mu.Unlock()
+	if line, ok := positions.lines[&body.Value.(List)[0]]; ok {
+		pos = fmt.Sprintf("%s:%d", pos, line)
+	}
+	return pos
+}
+
+// metaOf returns metadata of function, nil if a is not a function
+func metaOf(a Atom) *Meta {
+	switch a.Kind {
+	case AtomKindFunc:
+		return a.Value.(Func).meta
+	case AtomKindLambda:
+		if meta := a.Value.(Lambda).meta; meta != nil {
+			return meta
+		}
+		return &Meta{params: atomList(a.Value.(Lambda).params...).String()}
+	default:
+		return nil
+	}
+}
+
+// named returns function a named name if it has no name yet
+func named(a Atom, name Symbol) Atom {
+	meta := metaOf(a)
+	if meta == nil || meta.name != "" {
+		return a
+	}
+
+	renamed := *meta
+	renamed.name = string(name)
+	switch v := a.Value.(type) {
+	case Func:
+		v.meta = &renamed
+		return Atom{AtomKindFunc, v}
+	case Lambda:
+		v.meta = &renamed
+		return atomLambda(v)
+	default:
+		panic("unreachable")
+	}
+}
+
+// formatDoc formats documentation as name, argument list and indented doc
+func formatDoc(name string, meta Meta) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if meta.params != "" {
+		sb.WriteString(" " + meta.params)
+	}
+	for _, line := range strings.Split(meta.doc, "\n") {
+		if line != "" {
+			sb.WriteString("\n  " + line)
+		}
+	}
+	return sb.String()
+}
+
+func builtinDoc(env Env, args ...Atom) Atom {
+	if meta := metaOf(args[0]); meta != nil {
+		return atomString(formatDoc(meta.name, *meta))
+	}
+
+	var name string
+	switch a := args[0]; a.Kind {
+	case AtomKindString:
+		name = string(a.Value.(String))
+	case AtomKindSymbol:
+		name = string(a.Value.(Symbol))
+	default:
+		return lisherr("%s is not documented", a)
+	}
+
+	if meta, ok := specialForms[Symbol(name)]; ok {
+		return atomString(formatDoc(name, meta))
+	}
+	if res, ok := describe(env, name); ok {
+		return atomString(res)
+	}
+	return lisherr("%s is not documented", name)
+}
+
+// builtinApropos finds functions and special forms whose name or doc
+// contains pattern, ignoring case
+func builtinApropos(env Env, args ...Atom) Atom {
+	pattern := strings.ToLower(string(args[0].Value.(String)))
+	matches := func(name string, meta Meta) bool {
+		return strings.Contains(strings.ToLower(name), pattern) ||
+			strings.Contains(strings.ToLower(meta.doc), pattern)
+	}
+
+	names := []string{}
+	for name, meta := range specialForms {
+		if matches(string(name), meta) {
+			names = append(names, string(name))
+		}
+	}
+	for e := &env; ; e = e.Outer.Value {
+		for name, a := range e.bindings() {
+			if meta := metaOf(a); meta != nil && matches(string(name), *meta) {
+				names = append(names, string(name))
+			}
+		}
+		if !e.Outer.Valid {
+			break
+		}
+	}
+
+	slices.Sort(names)
+	return atomList(fun.Map[Atom](atomString[string], slices.Compact(names)...)...)
+}
+
+// builtinSource returns source of lambda, or where builtin is defined
+func builtinSource(args ...Atom) Atom {
+	switch a := args[0]; a.Kind {
+	case AtomKindLambda:
+		la, meta := a.Value.(Lambda), metaOf(a)
+		form := fmt.Sprintf("(%s %s %s)", fun.IF(la.isMacro, "macro", "fn"), meta.params, prStr(la.ast))
+		if meta.pos == "" {
+			return atomString(form)
+		}
+		return atomString(fmt.Sprintf("; %s\n%s", meta.pos, form))
+	case AtomKindFunc:
+		meta := a.Value.(Func).meta
+		return atomString(fmt.Sprintf("%s is builtin defined at %s", fun.IF(meta.name == "", "fn", meta.name), meta.pos))
+	default:
+		return lisherr("%s is not a function", a)
+	}
+}
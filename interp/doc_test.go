@@ -0,0 +1,52 @@
+package interp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceDocumented(t *testing.T) {
+	for name, fn := range namespace {
+		meta := fn.Value.(Func).meta
+		assert.Equal(t, string(name), meta.name)
+		assert.NotEmpty(t, meta.params, name)
+		assert.NotEmpty(t, meta.doc, name)
+		assert.Regexp(t, `^\w+\.go:\d+$`, meta.pos, name)
+	}
+	for name, meta := range specialForms {
+		assert.NotEmpty(t, meta.params, name)
+		assert.NotEmpty(t, meta.doc, name)
+	}
+}
+
+func TestDoc(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, atomString("first (list)\n  Returns first element of list, nil if list is empty."), eval(read(`(doc first)`), repl_env))
+	assert.Equal(t, atomString("if (predicate then [else])\n  Evaluates then if predicate is truthy, else otherwise."), eval(read(`(doc if)`), repl_env))
+
+	eval(read(`(set greet (fn (name) "Says hello." (join "hello " name)))`), repl_env)
+	assert.Equal(t, atomString("hello bob"), eval(read(`(greet "bob")`), repl_env))
+	assert.Equal(t, atomString("greet (name)\n  Says hello."), eval(read(`(doc greet)`), repl_env))
+	// name is kept when function is bound to another name
+	eval(read(`(set hi greet)`), repl_env)
+	assert.Equal(t, atomString("greet (name)\n  Says hello."), eval(read(`(doc hi)`), repl_env))
+
+	assert.Equal(t, atomList(atomString("greet"), atomString("hi")), eval(read(`(apropos "HELLO")`), repl_env))
+}
+
+func TestSource(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"inc.lish": `; increments
+(set inc (fn (n)
+  (+ n 1)))`,
+	})
+	path := filepath.Join(dir, "inc.lish")
+
+	repl_env := newEnvRepl()
+	repl_env.set("path", atomString(path))
+	eval(read(`(load-file path)`), repl_env)
+	assert.Equal(t, atomString("; "+path+":2\n(fn (n) (+ n 1))"), eval(read(`(source inc)`), repl_env))
+	assert.Regexp(t, `^first is builtin defined at core\.go:\d+$`, eval(read(`(source first)`), repl_env).String())
+}
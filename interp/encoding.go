@@ -0,0 +1,294 @@
+package interp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// inputText returns text to decode: string itself or stdout of successful
+// command, so that (json/parse (kubectl get pods -o json)) works
+func inputText(a Atom) (string, error) {
+	switch {
+	case a.Kind == AtomKindString:
+		return string(a.Value.(String)), nil
+	case isCommandResult(a):
+		res := a.Value.(Hash)
+		if code := res["exit_code"].Value.(Int); code != 0 {
+			return "", fmt.Errorf("command failed with exit code %d: %s", code, strings.TrimSpace(res["stderr"].String()))
+		}
+		if stdout := res["stdout"]; stdout.Kind == AtomKindString {
+			return string(stdout.Value.(String)), nil
+		}
+		return "", errors.New("command stdout is not captured")
+	default:
+		return "", fmt.Errorf("expected string or command result, not %s", a)
+	}
+}
+
+// fromGo converts decoded value to atom: objects become hashes, arrays become
+// lists and null becomes nil
+func fromGo(v any) (Atom, error) {
+	switch v := v.(type) {
+	case nil:
+		return atomNil, nil
+	case bool:
+		return atomBool(v), nil
+	case int:
+		return atomInt(v), nil
+	case int64:
+		return atomInt(v), nil
+	case uint64:
+		return atomInt(v), nil
+	case float64:
+		return atomFloat(v), nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return atomInt(n), nil
+		}
+		x, err := v.Float64()
+		if err != nil {
+			return Atom{}, err
+		}
+		return atomFloat(x), nil
+	case string:
+		return atomString(v), nil
+	case time.Time:
+		return atomString(v.Format(time.RFC3339Nano)), nil
+	case []any:
+		res := make([]Atom, len(v))
+		for i, x := range v {
+			a, err := fromGo(x)
+			if err != nil {
+				return Atom{}, err
+			}
+			res[i] = a
+		}
+		return atomList(res...), nil
+	case []map[string]any:
+		res := make([]Atom, len(v))
+		for i, x := range v {
+			a, err := fromGo(x)
+			if err != nil {
+				return Atom{}, err
+			}
+			res[i] = a
+		}
+		return atomList(res...), nil
+	case map[string]any:
+		res := make(map[string]Atom, len(v))
+		for k, x := range v {
+			a, err := fromGo(x)
+			if err != nil {
+				return Atom{}, err
+			}
+			res[k] = a
+		}
+		return atomHash(res), nil
+	case map[any]any:
+		// yaml mapping with non string keys
+		res := make(map[string]Atom, len(v))
+		for k, x := range v {
+			a, err := fromGo(x)
+			if err != nil {
+				return Atom{}, err
+			}
+			res[fmt.Sprint(k)] = a
+		}
+		return atomHash(res), nil
+	default:
+		return Atom{}, fmt.Errorf("unsupported value %v of type %T", v, v)
+	}
+}
+
+// toGo converts atom to value to encode. Keywords and symbols are encoded as
+// strings and nil as empty array.
+func toGo(a Atom) (any, error) {
+	switch a.Kind {
+	case AtomKindBool:
+		return bool(a.Value.(Bool)), nil
+	case AtomKindInt:
+		return int64(a.Value.(Int)), nil
+	case AtomKindFloat:
+		return float64(a.Value.(Float)), nil
+	case AtomKindString:
+		return string(a.Value.(String)), nil
+	case AtomKindKeyword:
+		return string(a.Value.(Keyword)), nil
+	case AtomKindSymbol:
+		return string(a.Value.(Symbol)), nil
+	case AtomKindList:
+		res := make([]any, len(a.Value.(List)))
+		for i, x := range a.Value.(List) {
+			v, err := toGo(x)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	case AtomKindHash:
+		res := make(map[string]any, len(a.Value.(Hash)))
+		for k, x := range a.Value.(Hash) {
+			v, err := toGo(x)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	case AtomKindRecord:
+		r := a.Value.(Record)
+		res := make(map[string]any, len(r.fields))
+		for i, x := range r.fields {
+			v, err := toGo(x)
+			if err != nil {
+				return nil, err
+			}
+			res[string(r.typ.fields[i])] = v
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("cannot encode %s", a)
+	}
+}
+
+// keywordFlags returns set of keyword flags given to builtin, failing on
+// ones it does not accept
+func keywordFlags(opts []Atom, allowed ...Keyword) (map[Keyword]bool, error) {
+	res := map[Keyword]bool{}
+	for _, opt := range opts {
+		kw := opt.Value.(Keyword)
+		if !slices.Contains(allowed, kw) {
+			return nil, fmt.Errorf("unknown option %s", opt)
+		}
+		res[kw] = true
+	}
+	return res, nil
+}
+
+func builtinJSONParse(args ...Atom) Atom {
+	text, err := inputText(args[0])
+	if err != nil {
+		return lisherr("json/parse: %s", err)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return lisherr("json/parse: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return lisherr("json/parse: unexpected data after value")
+	}
+
+	res, err := fromGo(v)
+	if err != nil {
+		return lisherr("json/parse: %s", err)
+	}
+	return res
+}
+
+// builtinJSONStringify encodes x as json on single line, or indented if
+// :pretty is given. Hash keys are always sorted.
+func builtinJSONStringify(args ...Atom) Atom {
+	opts, err := keywordFlags(args[1:], "pretty")
+	if err != nil {
+		return lisherr("json/stringify: %s", err)
+	}
+
+	v, err := toGo(args[0])
+	if err != nil {
+		return lisherr("json/stringify: %s", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if opts["pretty"] {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return lisherr("json/stringify: %s", err)
+	}
+	return atomString(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func builtinYAMLParse(args ...Atom) Atom {
+	text, err := inputText(args[0])
+	if err != nil {
+		return lisherr("yaml/parse: %s", err)
+	}
+
+	var v any
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+		return lisherr("yaml/parse: %s", err)
+	}
+
+	res, err := fromGo(v)
+	if err != nil {
+		return lisherr("yaml/parse: %s", err)
+	}
+	return res
+}
+
+// builtinYAMLStringify encodes x as yaml block document with sorted keys
+func builtinYAMLStringify(args ...Atom) Atom {
+	v, err := toGo(args[0])
+	if err != nil {
+		return lisherr("yaml/stringify: %s", err)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return lisherr("yaml/stringify: %s", err)
+	}
+	if err := enc.Close(); err != nil {
+		return lisherr("yaml/stringify: %s", err)
+	}
+	return atomString(buf.String())
+}
+
+func builtinTOMLParse(args ...Atom) Atom {
+	text, err := inputText(args[0])
+	if err != nil {
+		return lisherr("toml/parse: %s", err)
+	}
+
+	var v map[string]any
+	if _, err := toml.Decode(text, &v); err != nil {
+		return lisherr("toml/parse: %s", err)
+	}
+
+	res, err := fromGo(v)
+	if err != nil {
+		return lisherr("toml/parse: %s", err)
+	}
+	return res
+}
+
+// builtinTOMLStringify encodes hash as toml document with sorted keys
+func builtinTOMLStringify(args ...Atom) Atom {
+	v, err := toGo(args[0])
+	if err != nil {
+		return lisherr("toml/stringify: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return lisherr("toml/stringify: %s", err)
+	}
+	return atomString(buf.String())
+}
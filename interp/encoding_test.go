@@ -0,0 +1,59 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"json_parse": {`(json/parse "{\"a\": [1, 2.5, \"x\", true, null], \"b\": {}}")`, atomHash(map[string]Atom{
+			"a": atomList(atomInt(1), atomFloat(2.5), atomString("x"), atomBool(true), atomNil),
+			"b": atomHash(map[string]Atom{}),
+		})},
+		"json_parse_exponent":   {`(json/parse "1e3")`, atomFloat(1000.0)},
+		"json_parse_invalid":    {`(json/parse "{")`, lisherr("json/parse: unexpected EOF")},
+		"json_parse_trailing":   {`(json/parse "1 2")`, lisherr("json/parse: unexpected data after value")},
+		"json_stringify":        {`(json/stringify {:b (list 1 2.0 "<x>") :a :kw})`, atomString(`{"a":"kw","b":[1,2,"<x>"]}`)},
+		"json_stringify_pretty": {`(json/stringify {:b 1 :a (list)} :pretty)`, atomString("{\n  \"a\": [],\n  \"b\": 1\n}")},
+		"json_stringify_option": {`(json/stringify 1 :compact)`, lisherr("json/stringify: unknown option :compact")},
+		"json_stringify_fn":     {`(json/stringify (list +))`, lisherr("json/stringify: cannot encode #fn")},
+		"yaml_parse": {`(yaml/parse "a:\n  - 1\n  - 2.5\nb: x\n")`, atomHash(map[string]Atom{
+			"a": atomList(atomInt(1), atomFloat(2.5)),
+			"b": atomString("x"),
+		})},
+		"yaml_stringify": {`(yaml/stringify {:b (list 1 2) :a "x"})`, atomString("a: x\nb:\n  - 1\n  - 2\n")},
+		"toml_parse": {`(toml/parse "a = 1\n[b]\nc = \"x\"\n")`, atomHash(map[string]Atom{
+			"a": atomInt(1),
+			"b": atomHash(map[string]Atom{"c": atomString("x")}),
+		})},
+		"toml_stringify": {`(toml/stringify {:a 1})`, atomString("a = 1\n")},
+		"command_stdout": {`(json/parse (sh "-c" "echo '[1, 2]'"))`, atomList(atomInt(1), atomInt(2))},
+		"command_failed": {`(json/parse (sh "-c" "echo oops >&2; exit 3"))`, lisherr("json/parse: command failed with exit code 3: oops")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(read(tc.input), newEnvRepl()))
+		})
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	value := atomHash(map[string]Atom{
+		"name":  atomString("lish"),
+		"tags":  atomList(atomString("a"), atomString("b")),
+		"count": atomInt(3),
+		"ratio": atomFloat(0.5),
+		"ok":    atomBool(true),
+	})
+	for _, format := range []string{"json", "yaml", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			repl_env := newEnvRepl()
+			repl_env.set("value", value)
+			assert.Equal(t, value, eval(read(`(`+format+`/parse (`+format+`/stringify value))`), repl_env))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package interp
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/rprtr258/fun"
+)
+
+type Env struct {
+	Outer fun.Option[*Env]
+	Data  map[Symbol]Atom
+	// shell settings of current evaluation, inherited by callees
+	scope *shellScope
+	// guards Data, as environment is shared by goroutines started by go
+	mu *sync.RWMutex
+}
+
+func newEnv(outer fun.Option[*Env]) Env {
+	var scope *shellScope
+	if outer.Valid {
+		scope = outer.Value.scope
+	}
+	return Env{outer, map[Symbol]Atom{}, scope, &sync.RWMutex{}}
+}
+
+// newEnvRoot makes top level environment with data and Value protocol bound
+func newEnvRoot(data map[Symbol]Atom, scope *shellScope) Env {
+	data["Value"] = valueProtocol
+	return Env{fun.Invalid[*Env](), data, scope, &sync.RWMutex{}}
+}
+
+// newEnvRepl makes root environment of interpreter, which has its own current
+// directory and modules
+func newEnvRepl() Env {
+	return newEnvRoot(maps.Clone(namespace), &shellScope{dir: &workDir{}, modules: newModuleTable()})
+}
+
+// newEnvBind makes environment with params bound to args. Params are
+// patterns list of args is matched against as by let, false is returned if
+// it does not match.
+func newEnvBind(outer fun.Option[*Env], params []Atom, args []Atom, scope *shellScope) (Env, Atom, bool) {
+	env := newEnv(outer)
+	env.scope = scope
+	matched, err := bindPattern(atomList(params...), atomList(args...), env)
+	if !matched {
+		return Env{}, err, false
+	}
+	return env, Atom{}, true
+}
+
+// local returns value bound to key in this environment, outer ones are not
+// searched
+func (e Env) local(key Symbol) (Atom, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	a, ok := e.Data[key]
+	return a, ok
+}
+
+// bindings returns copy of names bound in this environment
+func (e Env) bindings() map[Symbol]Atom {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return maps.Clone(e.Data)
+}
+
+func (e Env) root() Env {
+	node := e
+	for node.Outer.Valid {
+		node = *node.Outer.Value
+	}
+	return node
+}
+
+func (e Env) get(key Symbol) (Atom, bool) {
+	for env := e; ; env = *env.Outer.Value {
+		if a, ok := env.local(key); ok {
+			return a, true
+		}
+		if !env.Outer.Valid {
+			return Atom{}, false
+		}
+	}
+}
+
+func (e Env) set(key Symbol, val Atom) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Data[key] = val
+}
+
+func (e Env) unset(key Symbol) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.Data, key)
+}
@@ -0,0 +1,85 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, eval(read("(set a 2)"), repl_env), atomInt(2))
+	assert.Equal(t, eval(read("(+ a 3)"), repl_env), atomInt(5))
+}
+
+func TestParse_end_of_input(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, eval(read("(+ 1 2"), repl_env), atomInt(3))
+	assert.Equal(t, eval(read("(+ 1 2 (+ 3 4"), repl_env), atomInt(10))
+	assert.Equal(t, eval(read("+ 1 2"), repl_env), atomInt(3))
+	assert.Equal(t, eval(read("+ 1 2 (+ 3 4"), repl_env), atomInt(10))
+}
+
+func TestEcho(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, eval(read("echo 92"), repl_env), atomString("92"))
+	// TODO: how to check "abc" is called
+	// assert.Equal(t, eval(read("abc"), repl_env), Err(LishErr::from(r#""abc" is not a function"#)));
+	// assert.Equal(t, eval(read(r#""abc""#), repl_env), Err(LishErr::from(r#""abc" is not a function"#)));
+}
+
+func TestPlus(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, eval(read("(+ 1 2 3)"), repl_env), atomInt(6))
+	assert.Equal(t, eval(read("(+ 1 2 (+ 1 2))"), repl_env), atomInt(6))
+}
+
+// TODO: add tests from history.txt
+
+func TestSignature(t *testing.T) {
+	validate := signature(arg(AtomKindString), optional(AtomKindInt, AtomKindFloat), variadic())
+	for name, tc := range map[string]struct {
+		args []Atom
+		msg  string
+	}{
+		"required":       {[]Atom{atomString("a")}, ""},
+		"optional":       {[]Atom{atomString("a"), atomFloat(1.0)}, ""},
+		"variadic":       {[]Atom{atomString("a"), atomInt(1), atomNil, atomBool(true)}, ""},
+		"too_few":        {[]Atom{}, "Expected at least 1 arguments"},
+		"wrong_kind":     {[]Atom{atomInt(1)}, "Expected 0-th argument to be string, not int64"},
+		"wrong_optional": {[]Atom{atomString("a"), atomNil}, "Expected 1-th argument to be int64 or f64, not list"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			msg, ok := validate(tc.args)
+			assert.Equal(t, tc.msg == "", ok)
+			assert.Equal(t, tc.msg, msg)
+		})
+	}
+
+	msg, ok := signature(arg(), optional())([]Atom{atomNil, atomNil, atomNil})
+	assert.False(t, ok)
+	assert.Equal(t, "Expected from 1 to 2 arguments", msg)
+}
+
+func TestNoPanic(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, atomNil, eval(read("(first ())"), repl_env))
+	assert.Equal(t, atomNil, eval(read("(rest ())"), repl_env))
+	assert.Equal(t, atomBool(false), eval(read("(= (list 1) (list 1 2))"), repl_env))
+	for _, input := range []string{
+		`(first 1)`,
+		`(/ 1 0)`,
+		`(if)`,
+		`(let)`,
+		`(fn)`,
+		`(exit "1")`,
+		`(setenv "A")`,
+	} {
+		assert.Equal(t, AtomKindError, eval(read(input), repl_env).Kind, input)
+	}
+	assert.Equal(t, atomInt(1), eval(read("(macroexpand 1)"), repl_env))
+	assert.Equal(t, `ERROR: "internal error: boom"`, rep("(panic)", newEnvRoot(
+		map[Symbol]Atom{"panic": atomFunc(func(...Atom) Atom { panic("boom") })},
+		&shellScope{},
+	)))
+}
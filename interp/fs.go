@@ -0,0 +1,344 @@
+package interp
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileInfo returns hash describing file
+func fileInfo(name string, info fs.FileInfo) Atom {
+	return atomHash(map[string]Atom{
+		"name":   atomString(name),
+		"size":   atomInt(info.Size()),
+		"mode":   atomString(info.Mode().String()),
+		"mtime":  atomString(info.ModTime().Format(time.RFC3339)),
+		"is-dir": atomBool(info.IsDir()),
+	})
+}
+
+// resolvePaths resolves paths given to builtin against current directory
+func resolvePaths(env Env, args []Atom) ([]string, error) {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		path, err := env.scope.filePath(string(arg.Value.(String)))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = path
+	}
+	return res, nil
+}
+
+// builtinLs returns hashes describing entries of directory sorted by name,
+// or hash of file itself if path is not directory
+func builtinLs(env Env, args ...Atom) Atom {
+	dir := "."
+	if len(args) == 1 {
+		dir = string(args[0].Value.(String))
+	}
+	path, err := env.scope.readPath(dir)
+	if err != nil {
+		return lisherr("ls: %s", err.Error())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return lisherr("ls: %s", err.Error())
+	}
+	if !info.IsDir() {
+		return atomList(fileInfo(filepath.Base(path), info))
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return lisherr("ls: %s", err.Error())
+	}
+	res := make([]Atom, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// removed after directory was read
+			continue
+		}
+		res = append(res, fileInfo(entry.Name(), info))
+	}
+	return atomList(res...)
+}
+
+func builtinStat(env Env, args ...Atom) Atom {
+	path, err := env.scope.readPath(string(args[0].Value.(String)))
+	if err != nil {
+		return lisherr("stat: %s", err.Error())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return lisherr("stat: %s", err.Error())
+	}
+	return fileInfo(filepath.Base(path), info)
+}
+
+func builtinExists(env Env, args ...Atom) Atom {
+	path, err := env.scope.readPath(string(args[0].Value.(String)))
+	if err != nil {
+		return lisherr("exists?: %s", err.Error())
+	}
+
+	_, err = os.Lstat(path)
+	return atomBool(err == nil)
+}
+
+// builtinMkdir makes directories along with their parents, existing ones are
+// left as is
+func builtinMkdir(env Env, args ...Atom) Atom {
+	paths, err := resolvePaths(env, args)
+	if err != nil {
+		return lisherr("mkdir: %s", err.Error())
+	}
+
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0o777); err != nil {
+			return lisherr("mkdir: %s", err.Error())
+		}
+	}
+	return atomNil
+}
+
+// builtinRm removes files and empty directories, or whole directory trees if
+// :recursive is given
+func builtinRm(env Env, args ...Atom) Atom {
+	names, flags := []Atom{}, []Atom{}
+	for _, arg := range args {
+		if arg.Kind == AtomKindKeyword {
+			flags = append(flags, arg)
+		} else {
+			names = append(names, arg)
+		}
+	}
+	opts, err := keywordFlags(flags, "recursive")
+	if err != nil {
+		return lisherr("rm: %s", err.Error())
+	}
+
+	paths, err := resolvePaths(env, names)
+	if err != nil {
+		return lisherr("rm: %s", err.Error())
+	}
+
+	remove := os.Remove
+	if opts["recursive"] {
+		remove = os.RemoveAll
+	}
+	for _, path := range paths {
+		if err := remove(path); err != nil {
+			return lisherr("rm: %s", err.Error())
+		}
+	}
+	return atomNil
+}
+
+// destination returns path src is moved or copied to: dst itself, or entry
+// named as src inside of dst if dst is directory
+func destination(src, dst string) string {
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		return filepath.Join(dst, filepath.Base(src))
+	}
+	return dst
+}
+
+func builtinMv(env Env, args ...Atom) Atom {
+	paths, err := resolvePaths(env, args)
+	if err != nil {
+		return lisherr("mv: %s", err.Error())
+	}
+
+	if err := os.Rename(paths[0], destination(paths[0], paths[1])); err != nil {
+		return lisherr("mv: %s", err.Error())
+	}
+	return atomNil
+}
+
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// builtinCp copies file, or directory with all its contents, keeping
+// permissions
+func builtinCp(env Env, args ...Atom) Atom {
+	paths, err := resolvePaths(env, args)
+	if err != nil {
+		return lisherr("cp: %s", err.Error())
+	}
+	src, dst := paths[0], destination(paths[0], paths[1])
+
+	if err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode())
+		}
+	}); err != nil {
+		return lisherr("cp: %s", err.Error())
+	}
+	return atomNil
+}
+
+// writeFile writes content to file, appending it if flag says so. Strings
+// are written as is, other values as they are printed.
+func writeFile(env Env, name string, flag int, args []Atom) Atom {
+	path, err := env.scope.filePath(string(args[0].Value.(String)))
+	if err != nil {
+		return lisherr("%s: %s", name, err.Error())
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0o666)
+	if err != nil {
+		return lisherr("%s: %s", name, err.Error())
+	}
+	if _, err := f.WriteString(args[1].String()); err != nil {
+		f.Close()
+		return lisherr("%s: %s", name, err.Error())
+	}
+	if err := f.Close(); err != nil {
+		return lisherr("%s: %s", name, err.Error())
+	}
+	return atomNil
+}
+
+func builtinSpit(env Env, args ...Atom) Atom {
+	return writeFile(env, "spit", os.O_TRUNC, args)
+}
+
+func builtinAppendFile(env Env, args ...Atom) Atom {
+	return writeFile(env, "append-file", os.O_APPEND, args)
+}
+
+// builtinTempFile creates empty file in temporary directory, returning its
+// path. Name is made of pattern, last * in it is replaced with random string.
+func builtinTempFile(env Env, args ...Atom) Atom {
+	if err := env.scope.files(); err != nil {
+		return lisherr("temp-file: %s", err.Error())
+	}
+
+	pattern := ""
+	if len(args) == 1 {
+		pattern = string(args[0].Value.(String))
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return lisherr("temp-file: %s", err.Error())
+	}
+	if err := f.Close(); err != nil {
+		return lisherr("temp-file: %s", err.Error())
+	}
+	return atomString(f.Name())
+}
+
+// builtinTempDir creates directory in temporary directory, returning its path
+func builtinTempDir(env Env, args ...Atom) Atom {
+	if err := env.scope.files(); err != nil {
+		return lisherr("temp-dir: %s", err.Error())
+	}
+
+	pattern := ""
+	if len(args) == 1 {
+		pattern = string(args[0].Value.(String))
+	}
+
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return lisherr("temp-dir: %s", err.Error())
+	}
+	return atomString(dir)
+}
+
+// builtinReadLines returns stream of lines of file without line endings. File
+// is read as stream is consumed and closed once it is exhausted or evaluation
+// is over, read error is the last item.
+func builtinReadLines(env Env, args ...Atom) Atom {
+	path, err := env.scope.readPath(string(args[0].Value.(String)))
+	if err != nil {
+		return lisherr("read-lines: %s", err.Error())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return lisherr("read-lines: %s", err.Error())
+	}
+
+	lines := make(Stream)
+	done := env.scope.context().Done()
+	go func() {
+		defer close(lines)
+		defer f.Close()
+
+		send := func(line Atom) bool {
+			select {
+			case lines <- line:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<30)
+		for scanner.Scan() {
+			if !send(atomString(scanner.Text())) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(lisherr("read-lines: %s", err.Error()))
+		}
+	}()
+	return Atom{AtomKindStream, lines}
+}
+
+// builtinCollect reads all items of stream into list
+func builtinCollect(args ...Atom) Atom {
+	res := []Atom{}
+	for item := range args[0].Value.(Stream) {
+		res = append(res, item)
+	}
+	return atomList(res...)
+}
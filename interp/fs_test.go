@@ -0,0 +1,98 @@
+package interp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	repl_env := newEnvRepl()
+	repl_env.set("dir", atomString(dir))
+	run := func(cmd string) Atom {
+		return eval(read(`(with-dir dir `+cmd+`)`), repl_env)
+	}
+
+	assert.Equal(t, atomNil, run(`(mkdir "a/b/c" "a/b")`))
+	assert.Equal(t, atomNil, run(`(spit "a/f.txt" "one\n")`))
+	assert.Equal(t, atomNil, run(`(append-file "a/f.txt" 2)`))
+	assert.Equal(t, atomString("one\n2"), run(`(slurp "a/f.txt")`))
+	assert.Equal(t, atomBool(true), run(`(exists? "a/b/c")`))
+	assert.Equal(t, atomBool(false), run(`(exists? "nope")`))
+
+	stat := run(`(stat "a/f.txt")`).Value.(Hash)
+	assert.Equal(t, atomString("f.txt"), stat["name"])
+	assert.Equal(t, atomInt(5), stat["size"])
+	assert.Equal(t, atomBool(false), stat["is-dir"])
+	assert.Regexp(t, `^-rw`, stat["mode"].Value)
+	assert.Equal(t, AtomKindString, stat["mtime"].Kind)
+
+	names := func(ls Atom) []string {
+		res := []string{}
+		for _, entry := range ls.Value.(List) {
+			res = append(res, entry.Value.(Hash)["name"].String())
+		}
+		return res
+	}
+	assert.Equal(t, []string{"b", "f.txt"}, names(run(`(ls "a")`)))
+	assert.Equal(t, []string{"f.txt"}, names(run(`(ls "a/f.txt")`)))
+
+	assert.Equal(t, atomNil, run(`(cp "a" "copy")`))
+	assert.Equal(t, atomString("one\n2"), run(`(slurp "copy/f.txt")`))
+	assert.Equal(t, atomBool(true), run(`(exists? "copy/b/c")`))
+	assert.Equal(t, atomNil, run(`(cp "a/f.txt" "copy/b")`))
+	assert.Equal(t, atomString("one\n2"), run(`(slurp "copy/b/f.txt")`))
+
+	assert.Equal(t, atomNil, run(`(mv "copy/f.txt" "copy/g.txt")`))
+	assert.Equal(t, atomNil, run(`(mv "copy/g.txt" "a/b")`))
+	assert.Equal(t, []string{"c", "g.txt"}, names(run(`(ls "a/b")`)))
+
+	assert.Equal(t, lisherr("rm: remove %s: directory not empty", filepath.Join(dir, "copy")), run(`(rm "copy")`))
+	assert.Equal(t, lisherr("rm: unknown option :force"), run(`(rm "copy" :force)`))
+	assert.Equal(t, atomNil, run(`(rm "copy" "a/f.txt" :recursive)`))
+	assert.Equal(t, []string{"a"}, names(run(`(ls)`)))
+}
+
+func TestReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("a\r\n\nb"), 0o644))
+
+	repl_env := newEnvRepl()
+	repl_env.set("path", atomString(path))
+	eval(read(`(set lines (read-lines path))`), repl_env)
+	assert.Equal(t, atomString("a"), eval(read(`(take! lines)`), repl_env))
+	assert.Equal(t, atomList(atomString(""), atomString("b")), eval(read(`(collect lines)`), repl_env))
+	assert.Equal(t, atomNil, eval(read(`(take! lines)`), repl_env))
+	assert.Equal(t, AtomKindError, eval(read(`(read-lines "/nonexistent")`), repl_env).Kind)
+
+	// file is closed once evaluation is over, though stream is not drained
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Repeat("line\n", 1000)), 0o644))
+	ctx, cancel := context.WithCancel(context.Background())
+	env := repl_env
+	env.scope = env.scope.withContext(ctx)
+	stream := eval(read(`(read-lines path)`), env).Value.(Stream)
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, ok := <-stream
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTempFiles(t *testing.T) {
+	repl_env := newEnvRepl()
+	file := eval(read(`(temp-file "lish-*.txt")`), repl_env)
+	dir := eval(read(`(temp-dir)`), repl_env)
+	defer os.Remove(string(file.Value.(String)))
+	defer os.Remove(string(dir.Value.(String)))
+
+	assert.Regexp(t, `lish-\d+\.txt$`, file.Value)
+	info, err := os.Stat(string(dir.Value.(String)))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
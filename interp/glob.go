@@ -0,0 +1,150 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// hasGlobMeta reports whether pattern needs to be expanded
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
+}
+
+// expandBraces expands a{b,c}d into abd and acd, braces might be nested
+func expandBraces(pattern string) []string {
+	depth, open := 0, -1
+	commas := []int{}
+	for i, c := range pattern {
+		switch c {
+		case '{':
+			if depth == 0 {
+				open = i
+				commas = commas[:0]
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth != 0 {
+				continue
+			}
+			if len(commas) == 0 {
+				// {x} is not an alternative, expand rest of pattern only
+				res := []string{}
+				for _, tail := range expandBraces(pattern[i+1:]) {
+					res = append(res, pattern[:i+1]+tail)
+				}
+				return res
+			}
+
+			prefix, suffix := pattern[:open], pattern[i+1:]
+			bounds := append(append([]int{open}, commas...), i)
+			res := []string{}
+			for j := 0; j+1 < len(bounds); j++ {
+				alt := pattern[bounds[j]+1 : bounds[j+1]]
+				res = append(res, expandBraces(prefix+alt+suffix)...)
+			}
+			return res
+		}
+	}
+	return []string{pattern}
+}
+
+// glob returns paths matching pattern, relative patterns are matched against
+// dir. Besides filepath.Match syntax, ** matches any number of directories
+// and {a,b} matches any of alternatives. Files starting with dot are matched
+// only if pattern segment starts with dot too.
+func glob(dir, pattern string) ([]string, error) {
+	res := []string{}
+	for _, pattern := range expandBraces(pattern) {
+		pattern, err := expandTilde(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		base, root := dir, ""
+		if filepath.IsAbs(pattern) {
+			base, root = "/", "/"
+		}
+
+		segments := strings.FieldsFunc(pattern, func(r rune) bool { return r == '/' })
+		if len(segments) > 0 && segments[len(segments)-1] == "**" {
+			// trailing ** matches files too
+			segments = append(segments, "*")
+		}
+		if err := globSegments(base, root, segments, &res); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res), nil
+}
+
+// globSegments appends to res files in dir which match segments, prefix is
+// the path of dir to be reported
+func globSegments(dir, prefix string, segments []string, res *[]string) error {
+	if len(segments) == 0 {
+		if prefix != "" {
+			*res = append(*res, prefix)
+		}
+		return nil
+	}
+
+	segment := segments[0]
+	if !hasGlobMeta(segment) {
+		next := filepath.Join(dir, segment)
+		if _, err := os.Lstat(next); err != nil {
+			return nil
+		}
+		return globSegments(next, filepath.Join(prefix, segment), segments[1:], res)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// not a directory or not readable, nothing matches inside
+		return nil
+	}
+
+	if segment == "**" {
+		if err := globSegments(dir, prefix, segments[1:], res); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if err := globSegments(filepath.Join(dir, entry.Name()), filepath.Join(prefix, entry.Name()), segments, res); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(segment, ".") {
+			continue
+		}
+
+		ok, err := filepath.Match(segment, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := globSegments(filepath.Join(dir, name), filepath.Join(prefix, name), segments[1:], res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
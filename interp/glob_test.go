@@ -0,0 +1,50 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBraces(t *testing.T) {
+	for pattern, res := range map[string][]string{
+		"abc":         {"abc"},
+		"a{b,c}d":     {"abd", "acd"},
+		"{a,b}{c,d}":  {"ac", "ad", "bc", "bd"},
+		"a{b,c{d,e}}": {"ab", "acd", "ace"},
+		"a{b}c":       {"a{b}c"},
+		"a{b,c":       {"a{b,c"},
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			assert.Equal(t, res, expandBraces(pattern))
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a.go", "b.go", "c.txt", ".hidden.go", "sub/d.go", "sub/deep/e.go"} {
+		path := filepath.Join(dir, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+
+	for pattern, res := range map[string][]string{
+		"*.go":        {"a.go", "b.go"},
+		".*":          {".hidden.go"},
+		"*.{go,txt}":  {"a.go", "b.go", "c.txt"},
+		"**/*.go":     {"a.go", "b.go", "sub/d.go", "sub/deep/e.go"},
+		"sub/**":      {"sub/d.go", "sub/deep", "sub/deep/e.go"},
+		"*/d.go":      {"sub/d.go"},
+		"nothing*":    {},
+		"sub/deep/e*": {"sub/deep/e.go"},
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			matches, err := glob(dir, pattern)
+			assert.NoError(t, err)
+			assert.Equal(t, res, matches)
+		})
+	}
+}
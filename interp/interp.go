@@ -0,0 +1,176 @@
+// Package interp is lish interpreter, which Go programs embed to evaluate
+// lish code as configuration or scripts.
+//
+//	in := interp.New(interp.WithSandbox(interp.Sandbox{NoExec: true, NoFiles: true}))
+//	in.Define("greet", func(name string) string { return "hello " + name })
+//	res, err := in.Eval(ctx, `(greet "world")`)
+package interp
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Sandbox restricts what evaluated code might do. Forbidden actions and
+// exceeded limits result in error atoms.
+type Sandbox struct {
+	// NoExec forbids running external commands and changing environment of
+	// lish process
+	NoExec bool
+	// Exec lists programs which are allowed to run even if NoExec is set.
+	// They are looked up in PATH of lish process and get its environment,
+	// ignoring with-env.
+	Exec []string
+	// NoFiles forbids reading and writing files, changing and listing
+	// directories and loading modules
+	NoFiles bool
+	// Read lists directories whose files might be read, listed and loaded
+	// even if NoFiles is set
+	Read []string
+	// MaxSteps limits number of forms evaluated by single Eval call, 0 means
+	// no limit
+	MaxSteps int
+	// MaxMemory limits number of bytes heap grows by during single Eval call,
+	// 0 means no limit. Heap is shared by the whole process, so allocations
+	// made by other goroutines meanwhile count too.
+	MaxMemory uint64
+}
+
+// Interp is lish interpreter with its own root environment. It might be used
+// from several goroutines at once.
+type Interp struct {
+	env Env
+}
+
+// Option configures interpreter made by New
+type Option func(*Interp)
+
+// WithArgs binds *ARGV* to list of args, it is nil by default
+func WithArgs(args ...string) Option {
+	return func(in *Interp) {
+		res := make([]Atom, len(args))
+		for i, arg := range args {
+			res[i] = atomString(arg)
+		}
+		in.env.set("*ARGV*", atomList(res...))
+	}
+}
+
+// WithSandbox restricts evaluated code as sandbox says
+func WithSandbox(sandbox Sandbox) Option {
+	return func(in *Interp) {
+		scope := *in.env.scope
+		scope.sandbox = sandbox
+		in.env.scope = &scope
+	}
+}
+
+// WithWarnings makes warnings about suspicious code, like match which might
+// match nothing, written to w once code is read
+func WithWarnings(w io.Writer) Option {
+	return func(in *Interp) {
+		scope := *in.env.scope
+		scope.warnings = w
+		in.env.scope = &scope
+	}
+}
+
+// WithBytecode makes evaluated code compiled into bytecode run by virtual
+// machine, forms compiler does not support are interpreted as usual
+func WithBytecode() Option {
+	return func(in *Interp) {
+		scope := *in.env.scope
+		scope.bytecode = true
+		in.env.scope = &scope
+	}
+}
+
+// New makes interpreter with builtins defined
+func New(opts ...Option) *Interp {
+	in := &Interp{newEnvRepl()}
+	in.env.set("*ARGV*", atomNil)
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// ExitError is returned when evaluated code calls exit
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit %d", e.Code)
+}
+
+// run calls f with root environment cancelled along with ctx. Error atom is
+// returned along with Error of it, Go panic becomes such error too.
+func (in *Interp) run(ctx context.Context, f func(Env) Atom) (res Atom, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case exitCode:
+			res, err = atomNil, &ExitError{int(r)}
+		default:
+			res = lisherr("internal error: %v", r)
+			err = res.Value.(Error)
+		}
+	}()
+
+	env := in.env
+	env.scope = env.scope.withContext(ctx).withLimits()
+	if res = f(env); res.Kind == AtomKindError {
+		return res, res.Value.(Error)
+	}
+	return res, nil
+}
+
+// Eval evaluates forms of src one by one, returning value of the last one or
+// first error
+func (in *Interp) Eval(ctx context.Context, src string) (Atom, error) {
+	return in.run(ctx, func(env Env) Atom {
+		return evalProgram(src, env)
+	})
+}
+
+// EvalLine evaluates line as typed in repl, where parens around top level
+// command call might be omitted
+func (in *Interp) EvalLine(ctx context.Context, line string) (Atom, error) {
+	return in.run(ctx, func(env Env) Atom {
+		form := readLine(line)
+		env.scope.warn(matchWarnings(form, env))
+		return evalForm(form, env)
+	})
+}
+
+// EvalFile evaluates file as load-file does
+func (in *Interp) EvalFile(ctx context.Context, path string) (Atom, error) {
+	return in.run(ctx, func(env Env) Atom {
+		return builtinLoadFile(env, atomString(path))
+	})
+}
+
+// Call calls lish function with args
+func (in *Interp) Call(ctx context.Context, fn Atom, args ...Atom) (Atom, error) {
+	return in.run(ctx, func(env Env) Atom {
+		return call(fn, args, env)
+	})
+}
+
+// Lookup returns value bound to name in root environment
+func (in *Interp) Lookup(name string) (Atom, bool) {
+	return lookup(in.env, Symbol(name))
+}
+
+// Define binds name to Go value converted by ToAtom. Go functions become
+// lish builtins, see ToAtom for how they are called.
+func (in *Interp) Define(name string, v any) error {
+	a, err := ToAtom(v)
+	if err != nil {
+		return fmt.Errorf("define %s: %w", name, err)
+	}
+	in.env.set(Symbol(name), named(a, Symbol(name)))
+	return nil
+}
@@ -0,0 +1,245 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpEval(t *testing.T) {
+	in := New(WithArgs("a", "b"))
+	for name, tc := range map[string]struct {
+		src string
+		res Atom
+		err string
+	}{
+		"value": {`(+ 1 2)`, atomInt(3), ""},
+		"forms": {"(set x 2)\n(* x 3)", atomInt(6), ""},
+		"argv":  {`*ARGV*`, atomList(atomString("a"), atomString("b")), ""},
+		"error": {`(throw "oops")`, lisherr("oops"), "oops"},
+		"stops": {`(throw "first") (throw "second")`, lisherr("first"), "first"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, err := in.Eval(context.Background(), tc.src)
+			assert.Equal(t, tc.res, res)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestInterpEvalLine(t *testing.T) {
+	t.Setenv("LISH_TEST", "value")
+	in := New()
+	for name, tc := range map[string]struct {
+		line string
+		res  Atom
+	}{
+		"call":     {`+ 1 2`, atomInt(3)},
+		"var":      {`$LISH_TEST`, atomString("value")},
+		"var_call": {`($LISH_TEST)`, lisherr("command not found: $LISH_TEST")},
+		"unset":    {`$LISH_TEST_UNSET`, atomNil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, _ := in.EvalLine(context.Background(), tc.line)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
+
+func TestInterpWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	in := New(WithWarnings(&buf))
+	res, err := in.Eval(context.Background(), `(set f (fn (x) (match x (1 :one)))) (f 1) (f 1)`)
+	assert.NoError(t, err)
+	assert.Equal(t, atomKeyword("one"), res)
+	assert.Equal(t, "warning: match on x is not exhaustive, add _ clause\n", buf.String())
+
+	// warnings are dropped unless asked for
+	_, err = New().Eval(context.Background(), `(match 1 (1 :one))`)
+	assert.NoError(t, err)
+}
+
+func TestInterpExit(t *testing.T) {
+	_, err := New().Eval(context.Background(), `(exit 3)`)
+	var exit *ExitError
+	assert.True(t, errors.As(err, &exit))
+	assert.Equal(t, 3, exit.Code)
+}
+
+func TestInterpDefine(t *testing.T) {
+	type user struct {
+		Name   string `lish:"name"`
+		Age    int    `lish:"age"`
+		Secret string `lish:"-"`
+	}
+
+	in := New()
+	assert.NoError(t, in.Define("greet", func(name string) string { return "hello " + name }))
+	assert.NoError(t, in.Define("sum", func(xs ...float64) float64 {
+		res := 0.0
+		for _, x := range xs {
+			res += x
+		}
+		return res
+	}))
+	assert.NoError(t, in.Define("div", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	}))
+	assert.NoError(t, in.Define("divmod", func(a, b int) (int, int) { return a / b, a % b }))
+	assert.NoError(t, in.Define("older", func(u user) user { u.Age++; return u }))
+	assert.NoError(t, in.Define("upper", func(ctx context.Context, words []string) []string {
+		res := make([]string, len(words))
+		for i, word := range words {
+			res[i] = strings.ToUpper(word)
+		}
+		return res
+	}))
+	assert.NoError(t, in.Define("config", map[string]any{"port": 8080, "hosts": []string{"a"}}))
+	assert.Error(t, in.Define("bad", make(chan int)))
+
+	for name, tc := range map[string]struct {
+		src string
+		res Atom
+	}{
+		"string":   {`(greet "world")`, atomString("hello world")},
+		"variadic": {`(sum 1 2.5 3)`, atomFloat(6.5)},
+		"result":   {`(div 7 2)`, atomInt(3)},
+		"error":    {`(div 1 0)`, lisherr("division by zero")},
+		"results":  {`(divmod 7 2)`, atomList(atomInt(3), atomInt(1))},
+		"struct": {`(older {:name "bob" :age 41 :Secret "x"})`, atomHash(map[string]Atom{
+			"name": atomString("bob"),
+			"age":  atomInt(42),
+		})},
+		"context": {`(upper '("a" "b"))`, atomList(atomString("A"), atomString("B"))},
+		"value":   {`(config :port)`, atomInt(8080)},
+		"arity":   {`(greet)`, lisherr("Expected exactly 1 arguments, but got ")},
+		"type":    {`(greet 1)`, lisherr("Expected 0-th argument to be string: cannot convert int64 1 to string")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, _ := in.Eval(context.Background(), tc.src)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
+
+func TestInterpFromAtom(t *testing.T) {
+	in := New()
+	fn, err := in.Eval(context.Background(), `(fn (x) {:twice (* 2 x) :tags '("a" "b")})`)
+	assert.NoError(t, err)
+
+	res, err := in.Call(context.Background(), fn, atomInt(21))
+	assert.NoError(t, err)
+
+	var out struct {
+		Twice int      `lish:"twice"`
+		Tags  []string `lish:"tags"`
+	}
+	assert.NoError(t, FromAtom(res, &out))
+	assert.Equal(t, 42, out.Twice)
+	assert.Equal(t, []string{"a", "b"}, out.Tags)
+
+	var generic any
+	assert.NoError(t, FromAtom(res, &generic))
+	assert.Equal(t, map[string]any{"twice": int64(42), "tags": []any{"a", "b"}}, generic)
+
+	var small int8
+	assert.Error(t, FromAtom(atomInt(1000), &small))
+}
+
+func TestInterpSandbox(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lib.lish"), []byte(`(set x 42)`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o644))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "link")))
+
+	in := New(WithSandbox(Sandbox{
+		NoExec:  true,
+		Exec:    []string{"sh"},
+		NoFiles: true,
+		Read:    []string{dir},
+	}))
+	for name, tc := range map[string]struct {
+		src string
+		res Atom
+	}{
+		"exec":     {`(date)`, lisherr("running date is disabled in sandbox")},
+		"exec_sh":  {`((sh "-c" "echo ok") :stdout)`, atomString("ok\n")},
+		"path":     {`((with-env {"PATH" "/nonexistent"} (sh "-c" "echo ok")) :stdout)`, atomString("ok\n")},
+		"setenv":   {`(setenv "PATH" "/nonexistent")`, lisherr("setenv: changing environment is disabled in sandbox")},
+		"slurp":    {`(slurp "/etc/passwd")`, lisherr("access to /etc/passwd is disabled in sandbox")},
+		"read":     {fmt.Sprintf(`(slurp %q)`, filepath.Join(dir, "lib.lish")), atomString("(set x 42)")},
+		"symlink":  {fmt.Sprintf(`(slurp %q)`, filepath.Join(dir, "link")), lisherr(fmt.Sprintf("access to %s is disabled in sandbox", filepath.Join(dir, "link")))},
+		"write":    {fmt.Sprintf(`(spit %q "x")`, filepath.Join(dir, "out")), lisherr("spit: file access is disabled in sandbox")},
+		"cd":       {`(cd "/")`, lisherr("cd: access to / is disabled in sandbox")},
+		"stdin":    {`(with-io {:stdin {:file "/etc/passwd"}} (sh "-c" "cat"))`, lisherr("access to /etc/passwd is disabled in sandbox")},
+		"stdout":   {fmt.Sprintf(`(with-io {:stdout {:file %q}} (sh "-c" "echo x"))`, filepath.Join(dir, "out")), lisherr("file access is disabled in sandbox")},
+		"stdin_in": {fmt.Sprintf(`((with-io {:stdin (file %q)} (sh "-c" "cat")) :stdout)`, filepath.Join(dir, "lib.lish")), atomString("(set x 42)")},
+		"load":     {fmt.Sprintf(`(load-file %q) x`, filepath.Join(dir, "lib.lish")), atomInt(42)},
+		"load_out": {fmt.Sprintf(`(load-file %q)`, filepath.Join(outside, "secret")), lisherr(fmt.Sprintf("access to %s is disabled in sandbox", filepath.Join(outside, "secret")))},
+		"pure":     {`(let (sq (fn (x) (* x x))) (sq 4))`, atomInt(16)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, _ := in.Eval(context.Background(), tc.src)
+			assert.Equal(t, tc.res, res)
+		})
+	}
+}
+
+func TestInterpLimits(t *testing.T) {
+	for name, tc := range map[string]struct {
+		sandbox Sandbox
+		src     string
+		res     Atom
+	}{
+		"steps":     {Sandbox{MaxSteps: 1000}, `(let (f (fn (n) (f (+ n 1)))) (f 0))`, lisherr("step limit of 1000 exceeded")},
+		"steps_ok":  {Sandbox{MaxSteps: 1000}, `(+ 1 2)`, atomInt(3)},
+		"memory":    {Sandbox{MaxMemory: 1 << 20}, `(let (f (fn (xs n) (f (cons (list n n n n) xs) (+ n 1)))) (f '() 0))`, lisherr("memory limit of 1048576 bytes exceeded")},
+		"memory_ok": {Sandbox{MaxMemory: 1 << 20}, `(+ 1 2)`, atomInt(3)},
+		"recover":   {Sandbox{MaxSteps: 1000}, `(if (ok? (let (f (fn (n) (f (+ n 1)))) (f 0))) 1 2)`, lisherr("step limit of 1000 exceeded")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := New(WithSandbox(tc.sandbox))
+			res, _ := in.Eval(context.Background(), tc.src)
+			assert.Equal(t, tc.res, res)
+			// each evaluation has its own limits
+			res, _ = in.Eval(context.Background(), `(+ 1 2)`)
+			assert.Equal(t, atomInt(3), res)
+		})
+	}
+}
+
+func TestInterpIsolated(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	lib, data := filepath.Join(dir, "lib.lish"), filepath.Join(outside, "data")
+	assert.NoError(t, os.WriteFile(lib, []byte(fmt.Sprintf(`(set x (slurp %q))`, data)), 0o644))
+	assert.NoError(t, os.WriteFile(data, []byte("data"), 0o644))
+
+	// module is loaded again by each interpreter, under its own sandbox
+	_, err := New().Eval(context.Background(), fmt.Sprintf(`(require %q)`, lib))
+	assert.NoError(t, err)
+	res, _ := New(WithSandbox(Sandbox{NoFiles: true, Read: []string{dir}})).Eval(context.Background(), fmt.Sprintf(`(require %q)`, lib))
+	assert.Equal(t, lisherr("require %s: access to %s is disabled in sandbox", lib, data), res)
+
+	// directory stack of one interpreter is not seen by other
+	first, second := New(), New()
+	_, err = first.Eval(context.Background(), fmt.Sprintf(`(pushd %q)`, dir))
+	assert.NoError(t, err)
+	res, _ = second.Eval(context.Background(), `(len (dirs))`)
+	assert.Equal(t, atomInt(1), res)
+	_, err = first.Eval(context.Background(), `(popd)`)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+// arity is number of arguments lambda clause takes
+type arity struct {
+	min int
+	max int // -1 if unbounded
+}
+
+func (a arity) accepts(n int) bool {
+	return n >= a.min && (a.max == -1 || n <= a.max)
+}
+
+func (a arity) String() string {
+	switch {
+	case a.max == -1:
+		return fmt.Sprintf("at least %d", a.min)
+	case a.min == a.max:
+		return fmt.Sprint(a.min)
+	default:
+		return fmt.Sprintf("%d to %d", a.min, a.max)
+	}
+}
+
+// paramsArity returns arity of params pattern. Params with default values
+// after the last required one are optional, rest and named params make
+// number of arguments unbounded.
+func paramsArity(params []Atom) (arity, error) {
+	parts, err := parseComposite(atomList(params...))
+	if err != nil {
+		return arity{}, err
+	}
+
+	res := arity{0, len(parts.items)}
+	for i, p := range parts.items {
+		if p.Kind != AtomKindSymbol {
+			res.min = i + 1
+		} else if _, ok := parts.defaults[string(p.Value.(Symbol))]; !ok {
+			res.min = i + 1
+		}
+	}
+	if parts.rest.Valid || len(parts.named) > 0 {
+		res.max = -1
+	}
+	return res, nil
+}
+
+// funcName returns name of function for error messages
+func funcName(meta *Meta) string {
+	if meta == nil || meta.name == "" {
+		return "fn"
+	}
+	return meta.name
+}
+
+// arityError reports that function is called with wrong number of arguments
+func arityError(meta *Meta, want arity, got int) Atom {
+	return lisherr("%s requires %s argument(s), but got %d", funcName(meta), want, got)
+}
+
+// implicitProgn returns form evaluating body forms one by one
+func implicitProgn(body []Atom) Atom {
+	if len(body) == 1 {
+		return body[0]
+	}
+	return atomList(append([]Atom{atomSymbol("progn")}, body...)...)
+}
+
+// makeLambda makes lambda of fn form (fn params [doc] & body) or multi-arity
+// one (fn [doc] :clauses (params & body) ...), whose clause is chosen by number
+// of arguments it is called with
+func makeLambda(l List, env Env) Atom {
+	forms := l[1:]
+	meta := &Meta{pos: sourcePos(env, l[len(l)-1])}
+	if len(forms) > 1 && forms[0].Kind == AtomKindString && forms[1] == atomKeyword("clauses") {
+		meta.doc, forms = string(forms[0].Value.(String)), forms[1:]
+	}
+
+	clauses := [][2]Atom{}
+	if forms[0] == atomKeyword("clauses") {
+		if len(forms) < 2 {
+			return lisherr("fn :clauses must be followed by clauses in %s", atomList(l...))
+		}
+		for _, form := range forms[1:] {
+			clause, ok := form.Value.(List)
+			if !ok || len(clause) < 2 || clause[0].Kind != AtomKindList {
+				return lisherr("fn clause must be (params & body), not %s", form)
+			}
+			clauses = append(clauses, [2]Atom{clause[0], implicitProgn(clause[1:])})
+		}
+	} else {
+		if len(forms) < 2 {
+			return lisherr("%q requires at least %d argument(s), but got %d in %s", "fn", 2, len(forms), atomList(l...))
+		}
+		if forms[0].Kind != AtomKindList {
+			return lisherr("fn params must be list, but it is %s", forms[0])
+		}
+		body := forms[1:]
+		if len(forms) > 2 && forms[1].Kind == AtomKindString {
+			// (fn params "doc" & body)
+			meta.doc, body = string(forms[1].Value.(String)), forms[2:]
+		}
+		clauses = append(clauses, [2]Atom{forms[0], implicitProgn(body)})
+	}
+
+	res := make([]Lambda, len(clauses))
+	params := make([]string, len(clauses))
+	id := new(byte)
+	for i, clause := range clauses {
+		a, err := paramsArity(clause[0].Value.(List))
+		if err != nil {
+			return lisherr("fn: invalid params: %s", err)
+		}
+		res[i] = Lambda{eval, clause[1], env, clause[0].Value.(List), false, meta, a, nil, id}
+		params[i] = clause[0].String()
+	}
+	meta.params = strings.Join(params, " ")
+
+	if len(res) == 1 {
+		return atomLambda(res[0])
+	}
+	lambda := res[0]
+	lambda.clauses = res
+	return atomLambda(lambda)
+}
+
+// newEnvCall makes environment to evaluate body of lambda clause taking
+// number of args in, returning the body along with it. Bindings are nested in
+// lambda closure, but shell scope is taken from the caller.
+func newEnvCall(lambda Lambda, caller Env, args []Atom) (Atom, Env, bool) {
+	clause := lambda
+	if len(lambda.clauses) > 0 {
+		i := slices.IndexFunc(lambda.clauses, func(c Lambda) bool { return c.arity.accepts(len(args)) })
+		if i == -1 {
+			return lisherr("%s has no clause taking %d argument(s)", funcName(lambda.meta), len(args)), Env{}, false
+		}
+		clause = lambda.clauses[i]
+	} else if !lambda.arity.accepts(len(args)) {
+		return arityError(lambda.meta, lambda.arity, len(args)), Env{}, false
+	}
+
+	env, err, ok := newEnvBind(fun.Valid(&clause.env), clause.params, args, caller.scope)
+	if !ok {
+		if err.Kind != AtomKindError {
+			err = lisherr("%s: arguments %s do not match params %s", funcName(lambda.meta), atomList(args...), atomList(clause.params...))
+		}
+		return err, Env{}, false
+	}
+	return clause.ast, env, true
+}
@@ -0,0 +1,47 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLambdaArity(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"too_few":          {`(progn (set f (fn (a b) a)) (f 1))`, lisherr("f requires 2 argument(s), but got 1")},
+		"too_many":         {`(progn (set f (fn (a b) a)) (f 1 2 3))`, lisherr("f requires 2 argument(s), but got 3")},
+		"rest":             {`(progn (set f (fn (a & xs) a)) (f))`, lisherr("f requires at least 1 argument(s), but got 0")},
+		"anonymous":        {`((fn (a) a))`, lisherr("fn requires 1 argument(s), but got 0")},
+		"optional":         {`(progn (set f (fn (a b :or {:b 10}) (+ a b))) (list (f 1) (f 1 2)))`, atomList(atomInt(11), atomInt(3))},
+		"optional_prev":    {`(progn (set f (fn (a b c :or {:b (* a 2) :c (+ b 1)}) (list a b c))) (f 1))`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"optional_arity":   {`(progn (set f (fn (a b :or {:b 10}) a)) (f))`, lisherr("f requires 1 to 2 argument(s), but got 0")},
+		"multi":            {`(progn (set f (fn :clauses ((a) (f a 10)) ((a b) (+ a b)))) (list (f 1) (f 1 2)))`, atomList(atomInt(11), atomInt(3))},
+		"multi_rest":       {`(progn (set f (fn :clauses ((a) :one) ((a & xs) :many))) (list (f 1) (f 1 2 3)))`, atomList(atomKeyword("one"), atomKeyword("many"))},
+		"multi_doc":        {`(progn (set f (fn "doc" :clauses ((a) a) ((a b) b))) (f 1 2))`, atomInt(2)},
+		"multi_arity":      {`(progn (set f (fn :clauses ((a) a) ((a b) b))) (f))`, lisherr("f has no clause taking 0 argument(s)")},
+		"destructure":      {`((fn ((a b) c) (list a b c)) (list 1 2) 3)`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"destructure_list": {`((fn ((a b) c) ((fn (x) x) a) (list b c)) (list 1 2) 3)`, atomList(atomInt(2), atomInt(3))},
+		"body":             {`(progn (set f (fn (x) (set y x) (+ y 1))) (f 1))`, atomInt(2)},
+		"doc_body":         {`(progn (set f (fn (x) "doc" (set y x) (+ y 1))) (f 1))`, atomInt(2)},
+		"multi_body":       {`(progn (set f (fn :clauses ((x) (set y x) (+ y 1)))) (f 1))`, atomInt(2)},
+		"multi_invalid":    {`(fn :clauses ((a)))`, lisherr("fn clause must be (params & body), not ((a))")},
+		"key":              {`(progn (set f (fn (cmd &key timeout retries) (list cmd timeout retries))) (f "ls" :retries 2))`, atomList(atomString("ls"), atomNil, atomInt(2))},
+		"key_default":      {`(progn (set f (fn (&key retries :or {:retries 3}) retries)) (list (f) (f :retries 1)))`, atomList(atomInt(3), atomInt(1))},
+		"key_unknown":      {`(progn (set f (fn (&key a) a)) (f :b 1))`, lisherr("f: arguments (:b 1) do not match params (&key a)")},
+		"key_odd":          {`(progn (set f (fn (&key a) a)) (f :a))`, lisherr("f: arguments (:a) do not match params (&key a)")},
+		"key_invalid":      {`(fn (a &key) a)`, lisherr("fn: invalid params: &key must be followed by names in (a &key)")},
+		"macro":            {`(progn (setmacro m (fn (a b) a)) (m 1))`, lisherr("m requires 2 argument(s), but got 1")},
+		"recur":            {`(loop (i 0) (recur))`, lisherr("recur requires 1 argument(s), but got 0")},
+		"compiled_arity":   {`((fn (a) a) 1 2)`, lisherr("fn requires 1 argument(s), but got 2")},
+		"printed":          {`(str (fn :clauses ((a) a) ((a b) b)))`, atomString("(fn :clauses ((a) a) ((a b) b))")},
+		"multi_macro_set":  {`(progn (set f (fn :clauses ((a) a) ((a b) b))) (apply f 1 2))`, atomInt(2)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(readValue(tc.input), newEnvRepl()))
+			assert.Equal(t, tc.res, evalCompiled(readValue(tc.input), newEnvRepl()))
+		})
+	}
+}
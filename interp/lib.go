@@ -0,0 +1,899 @@
+package interp
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/rprtr258/fun"
+)
+
+func quasiquote(ast Atom) Atom {
+	if ast.Kind == AtomKindList {
+		v := ast.Value.(List)
+		if len(v) > 0 {
+			// TODO: unquote with len(v) > 2 is meaningless
+			if v[0] == atomSymbol("unquote") && len(v) >= 2 {
+				return v[1]
+			}
+
+			res := []Atom{}
+			for i := len(v) - 1; i >= 0; i-- {
+				x := v[i]
+				if x.Kind == AtomKindList && len(x.Value.(List)) > 0 {
+					vv := x.Value.(List)
+					if vv[0] == atomSymbol("splice-unquote") {
+						if len(vv[1:]) == 0 {
+							// `(... ,@() res) -> `(... (splice-unquote) res)
+							res = []Atom{
+								atomSymbol("cons"),
+								atomList(atomSymbol("splice-unquote")),
+								atomList(res...),
+							}
+						} else {
+							// `(... ,@(s m t h) res) -> `(... s m t h res)
+							res = []Atom{
+								atomSymbol("concat"),
+								vv[1],
+								atomList(res...),
+							}
+						}
+						continue
+					}
+				}
+
+				res = []Atom{
+					atomSymbol("cons"),
+					quasiquote(x),
+					atomList(res...),
+				}
+			}
+			return atomList(res...)
+		}
+	}
+	return Atom{AtomKindList, List{atomSymbol("quote"), ast}}
+}
+
+func isMacroCall(ast Atom, env Env) bool {
+	if ast.Kind != AtomKindList {
+		return false
+	}
+
+	v := ast.Value.(List)
+	if len(v) == 0 || v[0].Kind != AtomKindSymbol {
+		return false
+	}
+
+	macroname := v[0].Value.(Symbol)
+	a, _ := lookup(env, macroname)
+	return a.Kind == AtomKindLambda && a.Value.(Lambda).isMacro
+}
+
+func macroexpand(ast Atom, env Env) Atom {
+	for isMacroCall(ast, env) {
+		if ast.Kind != AtomKindList {
+			panic("unreachable")
+		}
+
+		v := ast.Value.(List)
+		the_macro := eval(v[0], env)
+		if the_macro.Kind == AtomKindError {
+			return the_macro
+		}
+		args := v[1:]
+		if the_macro.Kind != AtomKindLambda {
+			panic("unreachable")
+		}
+
+		vmacro := the_macro.Value.(Lambda)
+		lambda_ast, lambda_env, ok := newEnvCall(vmacro, env, args)
+		if !ok {
+			return lambda_ast
+		}
+		ast = eval(lambda_ast, lambda_env)
+		if ast.Kind == AtomKindError {
+			return ast
+		}
+	}
+	return ast
+}
+
+type FormResult struct {
+	a   Atom
+	env fun.Option[Env] // valid if tail call optimisation
+}
+
+func eval_function_call(fn Atom, unevaluated_args []Atom, env Env) FormResult {
+	args := fun.Map[Atom](func(x Atom) Atom {
+		return eval(x, env)
+	}, unevaluated_args...)
+	return tailCall(fn, args, env)
+}
+
+// tailCall calls function with evaluated arguments, lambda body is returned
+// to be evaluated in tail position
+func tailCall(fn Atom, args []Atom, env Env) FormResult {
+	switch fn.Kind {
+	case AtomKindLambda:
+		v := fn.Value.(Lambda)
+		body, newEnv, ok := newEnvCall(v, env, args)
+		if !ok {
+			return FormResult{a: body}
+		}
+		return FormResult{body, fun.Valid(newEnv)}
+	case AtomKindFunc:
+		if f, ok := applied(fn, args); ok {
+			// function apply calls is in tail position too
+			return tailCall(f, args[1:], env)
+		}
+		return FormResult{a: fn.Value.(Func).call(env, args)}
+	case AtomKindString:
+		// TODO: inherit stdin, stdout by default, but pipe if piped
+		return FormResult{a: runCommand(env, string(fn.Value.(String)), args)}
+	case AtomKindHash:
+		return FormResult{a: callHash(fn.Value.(Hash), args)}
+	default:
+		return FormResult{a: lisherr("%s is not a function", fn)}
+	}
+}
+
+// callHash returns value of hash called with key
+func callHash(h Hash, args []Atom) Atom {
+	if len(args) != 1 {
+		return lisherr("Hash is not a function")
+	}
+
+	var key string
+	switch args[0].Kind {
+	case AtomKindString:
+		key = string(args[0].Value.(String))
+	case AtomKindKeyword:
+		key = string(args[0].Value.(Keyword))
+	default:
+		return lisherr("Hash key %v must be string or keyword", args[0])
+	}
+
+	value, ok := h[key]
+	if !ok {
+		return lisherr("Value was not found by key %v", args[0])
+	}
+	return value
+}
+
+// specialForms are evaluated by eval itself, not looked up in environment
+var specialForms = map[Symbol]Meta{
+	"quote":            {params: "(x)", doc: "Returns x unevaluated."},
+	"quasiquoteexpand": {params: "(x)", doc: "Returns form quasiquote of x is expanded to."},
+	"quasiquote":       {params: "(x)", doc: "Returns x unevaluated except for parts in unquote and splice-unquote."},
+	"macroexpand":      {params: "(form)", doc: "Returns form macro call is expanded to."},
+	"set":              {params: "(name value)", doc: "Binds name to value in current environment."},
+	"setmacro":         {params: "(name fn)", doc: "Defines macro name expanded by fn."},
+	"let":              {params: "((pattern value ...) & body)", doc: "Evaluates body with names of patterns bound to parts of values. Pattern is name, _ matching anything, literal, list of patterns with optional & rest, or hash of patterns by keys. List ones might end with :as name and :or {:name default}, hash ones are given them as (&hash {key pattern ...} :as name :or {:name default})."},
+	"with-env":         {params: "(vars & body)", doc: "Evaluates body with environment variables from vars hash, nil value unsets variable."},
+	"with-dir":         {params: "(dir & body)", doc: "Evaluates body in directory dir."},
+	"with-io":          {params: "(opts & body)", doc: "Evaluates body with :stdin, :stdout and :stderr of commands redirected as opts hash says."},
+	"with-timeout":     {params: "(ms & body)", doc: "Evaluates body, cancelling it and commands it runs after ms milliseconds."},
+	"match":            {params: "(x & (pattern [:when guard] & body))", doc: "Evaluates body of first clause whose pattern x matches and guard is truthy for, with names of pattern bound. Patterns are as in let."},
+	"loop":             {params: "((name value ...) & body)", doc: "Evaluates body with names bound to values, recur in tail position evaluates it again with names bound to its arguments."},
+	"recur":            {params: "(& values)", doc: "Evaluates body of innermost loop again with its names bound to values, must be in tail position."},
+	"while":            {params: "(test & body)", doc: "Evaluates body while test is truthy, returns nil."},
+	"dotimes":          {params: "((name n) & body)", doc: "Evaluates body with name bound to 0, 1, ... n-1, returns nil."},
+	"for":              {params: "((name seq ... :when test :let (name value ...)) & body)", doc: "Returns list of values of body for each binding of names to items of seqs, which are lists, streams, or strings and command results split into lines. Bindings :when test is falsy for are skipped."},
+	"defrecord":        {params: "(name (field ...))", doc: "Defines record kind name, constructor name taking values of fields, predicate name? and accessors name-field."},
+	"defprotocol":      {params: "(name & (method (x & params) [doc]))", doc: "Defines protocol name and its methods, which call implementation for kind of x given by extend."},
+	"extend":           {params: "(kind & protocol (method params & body) ...)", doc: "Implements methods of protocols for record or builtin kind, like int64 or string. Records implementing str, = and < of Value protocol are printed and compared by them."},
+	"progn":            {params: "(& body)", doc: "Evaluates forms of body, returns value of the last one."},
+	"if":               {params: "(predicate then [else])", doc: "Evaluates then if predicate is truthy, else otherwise."},
+	"and":              {params: "(& xs)", doc: "Returns first falsy x or the last one, rest are not evaluated."},
+	"or":               {params: "(& xs)", doc: "Returns first truthy x or the last one, rest are not evaluated."},
+	"eval":             {params: "(form)", doc: "Evaluates form in root environment."},
+	"fn":               {params: "(params [doc] & body)", doc: "Returns lambda, params are patterns as in let, the one after & is bound to list of rest arguments and names after &key to values following their keywords. Params with :or default values might be omitted. Multi-arity lambda (fn [doc] :clauses (params & body) ...) calls the first clause taking given number of arguments."},
+	"pipe":             {params: "(cmds pipes)", doc: "Runs commands connected by pipes."},
+	"provide":          {params: "(& names)", doc: "Lists names module exports, all names are exported if module provides none."},
+	"export":           {params: "(& names)", doc: "Same as provide."},
+	"go":               {params: "(& body)", doc: "Evaluates body in goroutine, returns future of its value."},
+}
+
+// evalBody evaluates all forms of body but the last one, which is returned to
+// be evaluated in tail position. If some form fails, its error is returned
+// with false.
+func evalBody(body []Atom, env Env) (Atom, bool) {
+	if len(body) == 0 {
+		return atomNil, true
+	}
+
+	for _, form := range body[:len(body)-1] {
+		if res := eval(form, env); res.Kind == AtomKindError {
+			return res, false
+		}
+	}
+	return body[len(body)-1], true
+}
+
+// applyMeta is meta of apply builtin, whose calls are tail calls of function
+// it is given
+var applyMeta *Meta
+
+// applied returns function apply is called with, false if fn is not apply or
+// is called with something else, so it reports error itself
+func applied(fn Atom, args []Atom) (Atom, bool) {
+	if fn.Kind != AtomKindFunc || fn.Value.(Func).meta != applyMeta || len(args) == 0 {
+		return Atom{}, false
+	}
+	switch args[0].Kind {
+	case AtomKindFunc, AtomKindLambda:
+		return args[0], true
+	default:
+		return Atom{}, false
+	}
+}
+
+// call calls function with already evaluated arguments
+func call(fn Atom, args []Atom, env Env) Atom {
+	switch fn.Kind {
+	case AtomKindFunc, AtomKindLambda:
+		fr := tailCall(fn, args, env)
+		if !fr.env.Valid {
+			return fr.a
+		}
+		return eval(fr.a, fr.env.Value)
+	default:
+		return lisherr("%s is not a function", fn)
+	}
+}
+
+func eval(ast Atom, env Env) Atom {
+	for {
+		// long running loops stop on timeout or interrupt
+		if cancelled, ok := env.scope.cancelled(); ok {
+			return cancelled
+		}
+		if exceeded, ok := env.scope.step(); ok {
+			return exceeded
+		}
+
+		ast = macroexpand(ast, env)
+		if ast.Kind == AtomKindError {
+			return ast
+		}
+		// evaluate form
+		switch ast.Kind {
+		case AtomKindList:
+			l := ast.Value.(List)
+			// nil is evaluated to nil
+			if len(l) == 0 {
+				return atomNil
+			}
+			lish_assert_args := func(cmd string, args_count int) Atom {
+				return lisherr("%q requires %d argument(s), but got %d in %s", cmd, args_count, len(l[1:]), ast)
+			}
+			lish_assert_min_args := func(cmd string, args_count int) Atom {
+				return lisherr("%q requires at least %d argument(s), but got %d in %s", cmd, args_count, len(l[1:]), ast)
+			}
+
+			if l[0].Kind == AtomKindSymbol {
+				switch s := l[0].Value.(Symbol); s {
+				case "quote":
+					if len(l[1:]) != 1 {
+						return lish_assert_args("quote", 1)
+					}
+					return l[1]
+				case "quasiquoteexpand":
+					if len(l[1:]) != 1 {
+						return lish_assert_args("quasiquoteexpand", 1)
+					}
+					return quasiquote(l[1])
+				case "quasiquote":
+					if len(l[1:]) != 1 {
+						return lish_assert_args("quasiquote", 1)
+					}
+
+					ast = quasiquote(l[1])
+					continue
+				case "macroexpand":
+					if len(l[1:]) != 1 {
+						return lish_assert_args("macroexpand", 1)
+					}
+
+					// only calls might be macro calls
+					if l[1].Kind != AtomKindList || len(l[1].Value.(List)) == 0 {
+						return l[1]
+					}
+
+					head := l[1].Value.(List)[0]
+					if err := eval(head, env); err.Kind == AtomKindError {
+						return err
+					}
+					return macroexpand(l[1], env)
+				case "set":
+					if len(l[1:]) != 2 {
+						return lish_assert_args("set", 2)
+					}
+
+					value := eval(l[2], env)
+					if value.Kind == AtomKindError {
+						return value
+					}
+					if l[1].Kind != AtomKindSymbol {
+						return lisherr("%s is not a symbol", l[1])
+					}
+					name := l[1].Value.(Symbol)
+					env.set(name, named(value, name))
+					return value
+				case "setmacro":
+					if len(l[1:]) != 2 {
+						return lish_assert_args("setmacro", 2)
+					}
+
+					v := eval(l[2], env)
+					if v.Kind == AtomKindError {
+						return v
+					}
+					if v.Kind != AtomKindLambda {
+						return lisherr("Macro is not lambda")
+					}
+					if l[1].Kind != AtomKindSymbol {
+						return lisherr("%s is not a symbol", l[1])
+					}
+
+					la := v.Value.(Lambda)
+					la.isMacro = true
+					name := l[1].Value.(Symbol)
+					env.set(name, named(atomLambda(la), name))
+					return atomNil
+				case "let":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("let", 1)
+					}
+					if l[1].Kind != AtomKindList {
+						return lisherr("Let bindings is not a list, but a %s", l[1])
+					}
+
+					bindings := l[1].Value.(List)
+					if len(bindings)%2 != 0 {
+						return lisherr("'let' requires even number of arguments, but got %d in %s", len(l[1:]), ast)
+					}
+
+					outer := env
+					let_env := newEnv(fun.Valid(&outer))
+					for i := 0; i < len(bindings); i += 2 {
+						pattern := bindings[i]
+						var_value := eval(bindings[i+1], let_env)
+						if var_value.Kind == AtomKindError {
+							return var_value
+						}
+						matched, err := bindPattern(pattern, var_value, let_env)
+						if err.Kind == AtomKindError {
+							return err
+						}
+						if !matched {
+							return lisherr("%s does not match pattern %s", var_value, pattern)
+						}
+					}
+
+					body, ok := evalBody(l[2:], let_env)
+					if !ok {
+						return body
+					}
+					ast, env = body, let_env
+				case "with-env":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("with-env", 1)
+					}
+
+					vars := eval(l[1], env)
+					if vars.Kind == AtomKindError {
+						return vars
+					}
+					if vars.Kind != AtomKindHash {
+						return lisherr("with-env variables must be hash, not %s", vars)
+					}
+
+					overrides := map[string]fun.Option[string]{}
+					for k, v := range vars.Value.(Hash) {
+						switch v.Kind {
+						case AtomKindString:
+							overrides[k] = fun.Valid(string(v.Value.(String)))
+						case AtomKindList:
+							if len(v.Value.(List)) != 0 {
+								return lisherr("with-env variable %s value must be string or nil, not %s", k, v)
+							}
+							overrides[k] = fun.Invalid[string]()
+						default:
+							overrides[k] = fun.Valid(v.String())
+						}
+					}
+
+					outer := env
+					scoped_env := newEnv(fun.Valid(&outer))
+					scoped_env.scope = env.scope.withEnv(overrides)
+					body, ok := evalBody(l[2:], scoped_env)
+					if !ok {
+						return body
+					}
+					ast, env = body, scoped_env
+				case "with-dir":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("with-dir", 1)
+					}
+
+					dir := eval(l[1], env)
+					if dir.Kind == AtomKindError {
+						return dir
+					}
+					if dir.Kind != AtomKindString {
+						return lisherr("with-dir directory must be string, not %s", dir)
+					}
+
+					path, err := env.scope.readPath(string(dir.Value.(String)))
+					if err != nil {
+						return lisherr("with-dir: %s", err.Error())
+					}
+					if info, err := os.Stat(path); err != nil {
+						return lisherr("with-dir: %s", err.Error())
+					} else if !info.IsDir() {
+						return lisherr("with-dir: %s is not a directory", path)
+					}
+
+					outer := env
+					scoped_env := newEnv(fun.Valid(&outer))
+					scoped_env.scope = env.scope.withDir(path)
+					body, ok := evalBody(l[2:], scoped_env)
+					if !ok {
+						return body
+					}
+					ast, env = body, scoped_env
+				case "with-timeout":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("with-timeout", 1)
+					}
+
+					ms := eval(l[1], env)
+					if ms.Kind == AtomKindError {
+						return ms
+					}
+					if ms.Kind != AtomKindInt || ms.Value.(Int) <= 0 {
+						return lisherr("with-timeout timeout must be positive int, not %s", ms)
+					}
+
+					outer := env
+					scoped_env := newEnv(fun.Valid(&outer))
+					scope, cancel := env.scope.withTimeout(time.Duration(ms.Value.(Int)) * time.Millisecond)
+					defer cancel()
+					scoped_env.scope = scope
+					// body is not evaluated in tail position, so that timeout
+					// is cancelled once it is done
+					body, ok := evalBody(l[2:], scoped_env)
+					if !ok {
+						return body
+					}
+					return eval(body, scoped_env)
+				case "with-io":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("with-io", 1)
+					}
+
+					opts := eval(l[1], env)
+					if opts.Kind == AtomKindError {
+						return opts
+					}
+					if opts.Kind != AtomKindHash {
+						return lisherr("with-io options must be hash, not %s", opts)
+					}
+
+					spec, err := parseIOSpec(opts.Value.(Hash))
+					if err != nil {
+						return lisherr("with-io: %s", err.Error())
+					}
+
+					outer := env
+					scoped_env := newEnv(fun.Valid(&outer))
+					scoped_env.scope = env.scope.withIO(spec)
+					body, ok := evalBody(l[2:], scoped_env)
+					if !ok {
+						return body
+					}
+					ast, env = body, scoped_env
+				case "match":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("match", 1)
+					}
+
+					body, match_env, ok := evalMatch(ast, env)
+					if !ok {
+						return body
+					}
+					ast, env = body, match_env
+				case "loop":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("loop", 1)
+					}
+
+					body, loop_env, ok := evalLoop(l, env)
+					if !ok {
+						return body
+					}
+					ast, env = body, loop_env
+				case "recur":
+					body, loop_env, ok := evalRecur(l[1:], env)
+					if !ok {
+						return body
+					}
+					ast, env = body, loop_env
+				case "while":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("while", 1)
+					}
+					return evalWhile(l, env)
+				case "dotimes":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("dotimes", 1)
+					}
+					return evalDotimes(l, env)
+				case "for":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("for", 1)
+					}
+					return evalFor(l, env)
+				case "defrecord":
+					if len(l[1:]) != 2 {
+						return lish_assert_args("defrecord", 2)
+					}
+					return evalDefrecord(l, env)
+				case "defprotocol":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("defprotocol", 1)
+					}
+					return evalDefprotocol(l, env)
+				case "extend":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("extend", 1)
+					}
+					return evalExtend(l, env)
+				case "progn":
+					body, ok := evalBody(l[1:], env)
+					if !ok {
+						return body
+					}
+					ast = body
+				case "if":
+					if n := len(l[1:]); n != 2 && n != 3 {
+						return lisherr("%q requires 2 or 3 argument(s), but got %d in %s", "if", n, ast)
+					}
+
+					predicate := eval(l[1], env)
+					if predicate.Kind == AtomKindError {
+						return predicate
+					}
+					if !truthy(predicate) {
+						if len(l[1:]) == 3 {
+							ast = l[3]
+						} else {
+							return atomNil
+						}
+					} else {
+						ast = l[2]
+					}
+				case "and", "or":
+					// evaluate until value decides result, return that value
+					if len(l[1:]) == 0 {
+						return atomBool(s == "and")
+					}
+
+					for _, item := range l[1 : len(l)-1] {
+						value := eval(item, env)
+						if value.Kind == AtomKindError {
+							return value
+						}
+						if truthy(value) == (s == "or") {
+							return value
+						}
+					}
+					ast = l[len(l)-1]
+				case "eval":
+					if len(l[1:]) != 1 {
+						return lish_assert_args("eval", 1)
+					}
+					ast = eval(l[1], env)
+					if ast.Kind == AtomKindError {
+						return ast
+					}
+					env = env.root()
+					continue
+				case "fn":
+					if len(l[1:]) < 1 {
+						return lish_assert_min_args("fn", 1)
+					}
+					return makeLambda(l, env)
+				case "provide", "export":
+					// names which module exports when required
+					names := []Atom{}
+					for _, name := range l[1:] {
+						if name.Kind != AtomKindSymbol {
+							return lisherr("%s is not a symbol", name)
+						}
+						names = append(names, name)
+					}
+
+					root := env.root()
+					if provided, ok := root.local("*EXPORTS*"); ok {
+						names = append(slices.Clone(provided.Value.(List)), names...)
+					}
+					root.set("*EXPORTS*", atomList(names...))
+					return atomNil
+				case "go":
+					body := l[1:]
+					goEnv := env
+					scope, stop := env.scope.withSpawn()
+					goEnv.scope = scope
+					return Atom{AtomKindFuture, spawn(func() Atom {
+						defer stop()
+						last, ok := evalBody(body, goEnv)
+						if !ok {
+							return last
+						}
+						return eval(last, goEnv)
+					})}
+				case "pipe":
+					if len(l[1:]) != 2 {
+						return lish_assert_args("pipe", 2)
+					}
+
+					if l[1].Kind != AtomKindList {
+						return lisherr("pipe cmds must be list, not %s", l[1])
+					}
+
+					cmds := l[1].Value.(List)
+					if len(cmds)%2 != 0 {
+						return lisherr("pipe cmds count must be even, not %d", len(cmds))
+					}
+
+					if l[2].Kind != AtomKindList {
+						return lisherr("pipe pipes must be list, not %s", l[2])
+					}
+					pipes := l[2].Value.(List)
+					if len(pipes)%2 != 0 {
+						return lisherr("pipe pipes count must be even, not %d", len(pipes))
+					}
+
+					// READ PIPES
+					type EdgeBeginKind int
+					const (
+						BProcessStdout EdgeBeginKind = iota
+						BProcessStderr
+						BFile
+						BNull
+						BInherit
+						BString
+					)
+					type EdgeBegin struct {
+						kind EdgeBeginKind
+						s    String
+					}
+
+					type EdgeEndKind int
+					const (
+						EProcessStdin EdgeEndKind = iota
+						EFile
+						ENull
+						EInherit
+						// EString(String),
+					)
+					type EdgeEnd struct {
+						kind EdgeEndKind
+						s    String
+					}
+					// type Vertex struct {
+					// 	cmd_name string
+					// 	stdin    EdgeBegin
+					// 	stdout   EdgeEnd
+					// 	stderr   EdgeEnd
+					// }
+					for i := 0; i < len(pipes)/2; i++ {
+						var from EdgeBegin
+						switch v := pipes[i*2]; v.Kind {
+						case AtomKindList:
+							v := v.Value.(List)
+							if len(v) != 2 || v[0].Kind != AtomKindString || v[1].Kind != AtomKindString {
+								return lisherr("unknown pipe beginning: %s", pipes[i*2])
+							}
+							v0 := v[0]
+							v1 := v[1]
+
+							pp := v0.Value.(String)
+							s := v1.Value.(String)
+							switch pp {
+							case "stdout":
+								from = EdgeBegin{BProcessStdout, s}
+							case "stderr":
+								from = EdgeBegin{BProcessStderr, s}
+							case "file":
+								from = EdgeBegin{BFile, s}
+							case "string":
+								from = EdgeBegin{BString, s}
+							default:
+								return lisherr("(%s %s) can't be pipe beginning", pp, s)
+							}
+						case AtomKindString:
+							switch x := v.Value.(String); x {
+							case "null":
+								from = EdgeBegin{BNull, ""}
+							case "inherit":
+								from = EdgeBegin{BInherit, ""}
+							default:
+								return lisherr("unknown pipe beginning: %s", x)
+							}
+						default:
+							return lisherr("unknown pipe beginning: %s", v)
+						}
+
+						var into EdgeEnd
+						switch v := pipes[i*2+1]; v.Kind {
+						case AtomKindList:
+							v := v.Value.(List)
+							if len(v) != 2 || v[0].Kind != AtomKindString || v[1].Kind != AtomKindString {
+								return lisherr("unknown pipe ending: %s", pipes[i*2+1])
+							}
+							v0 := v[0]
+							v1 := v[1]
+
+							pp := v0.Value.(String)
+							s := v1.Value.(String)
+							switch pp {
+							case "stdin":
+								into = EdgeEnd{EProcessStdin, s}
+							case "file":
+								into = EdgeEnd{EFile, s}
+							default:
+								return lisherr("(%s %s) can't be pipe ending", pp, s)
+							}
+						case AtomKindString:
+							switch v := v.Value.(String); v {
+							case "null":
+								into = EdgeEnd{ENull, ""}
+							case "inherit":
+								into = EdgeEnd{EInherit, ""}
+							default:
+								return lisherr("unknown pipe ending: %s", v)
+							}
+						default:
+							return lisherr("unknown pipe ending: %s", v)
+						}
+						// edges are only validated, processes are not connected yet
+						_, _ = from, into
+					}
+
+					// SPAWN PROCESSES AND PIPE THEM
+					childs := map[string]*exec.Cmd{}
+					for i := 0; i < len(cmds)/2; i++ {
+						if x := cmds[i*2]; x.Kind != AtomKindString {
+							return lisherr("cmd name must be string, not %s", x)
+						}
+						cmd_name := string(cmds[i*2].Value.(String))
+						if _, ok := childs[cmd_name]; ok {
+							return lisherr("cmd with name %s is declared at least twice, only one must survive", cmd_name)
+						}
+						if x := cmds[i*2+1]; x.Kind != AtomKindList {
+							return lisherr("cmd args must be list, not %s", x)
+						}
+						args := cmds[i*2+1].Value.(List)
+						if len(args) == 0 {
+							return lisherr("cmd %s is empty", cmd_name)
+						}
+						if x := args[0]; x.Kind != AtomKindString {
+							return lisherr("cmd must be string, not %s", x)
+						}
+						program := string(args[0].Value.(String))
+						program_args, err := commandArgs(env, args[1:])
+						if err != nil {
+							return lisherr("%s", err)
+						}
+						var stdin, stdout, stderr bytes.Buffer
+						child, err := newCommand(env, program, program_args)
+						if err != nil {
+							return lisherr("%s", err)
+						}
+						child.Stdin = &stdin
+						child.Stdout = &stdout
+						child.Stderr = &stderr
+						childs[cmd_name] = child
+					}
+
+					// RETURN RESULTS
+					// let status = child.wait().unwrap();
+					// let mut res = fnv::FnvHashMap::default();
+					// res.insert("exit_code".to_owned(), match status.code() {
+					//     Some(exit_code) => Atom::Int(exit_code.into()),
+					//     None => Atom::Nil,
+					// });
+					// res.insert("stdout".to_owned(), {
+					//     let mut stdout = String::new();
+					//     match child.stdout {
+					//         Some(mut s) => {
+					//             s.read_to_string(&mut stdout).unwrap();
+					//             Atom::String(stdout)
+					//         },
+					//         None => Atom::Nil,
+					//     }
+					// });
+					// let mut stderr = String::new();
+					// match child.stderr {
+					//     Some(mut s) => {
+					//         s.read_to_string(&mut stderr).unwrap();
+					//     },
+					//     None => {},
+					// }
+					// res.insert("stderr".to_owned(), Atom::String(stderr));
+					// FormResult::Return(Atom::Hash(std::rc::Rc::new(res)))
+					return atomNil
+				default:
+					// TODO: call shell
+					fn := atomString(s)
+					if ss, ok := lookup(env, s); ok {
+						fn = ss
+					}
+
+					// lisherr(format("Not found '{}'", key)),
+					fr := eval_function_call(fn, l[1:], env)
+					if fr.env.Valid { // tail call optimisation
+						ast = fr.a
+						env = fr.env.Value
+						continue
+					} else {
+						return fr.a
+					}
+				}
+			} else {
+				// TODO: call shell
+				fn := eval(l[0], env)
+				if fn.Kind == AtomKindError {
+					return fn
+				}
+				fr := eval_function_call(fn, l[1:], env)
+				if fr.env.Valid { // tail call optimisation
+					ast = fr.a
+					env = fr.env.Value
+					continue
+				} else {
+					return fr.a
+				}
+			}
+		// hash literal values are evaluated
+		case AtomKindHash:
+			res := make(map[string]Atom, len(ast.Value.(Hash)))
+			for k, v := range ast.Value.(Hash) {
+				value := eval(v, env)
+				if value.Kind == AtomKindError {
+					return value
+				}
+				res[k] = value
+			}
+			return atomHash(res)
+		// others are evaluated to themselves
+		case AtomKindSymbol:
+			return evalSymbol(ast.Value.(Symbol), env)
+		default:
+			return ast
+		}
+	}
+}
+
+// evalTop evaluates top level form, so that Go panic becomes lish error
+// instead of killing lish
+func evalTop(ast Atom, env Env) (res Atom) {
+	defer func() {
+		if r := recover(); r != nil {
+			if code, ok := r.(exitCode); ok {
+				panic(code)
+			}
+			res = lisherr("internal error: %v", r)
+		}
+	}()
+	return eval(ast, env)
+}
+
+func rep(input string, env Env) string {
+	return evalTop(read(input), env).String()
+}
@@ -1,14 +1,15 @@
-package main
+package interp
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/rprtr258/fun"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestQuasiquote(t *testing.T) {
-	t.Skip() // TODO: not passing
 	list := atomList
 	symbol := atomSymbol
 	for name, tc := range map[string]struct {
@@ -490,3 +491,88 @@ func TestEval(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvVars(t *testing.T) {
+	t.Setenv("LISH_TEST", "outer")
+	repl_env := newEnvRepl()
+	assert.Equal(t, atomString("outer"), eval(atomSymbol("$LISH_TEST"), repl_env))
+	assert.Equal(t, atomNil, eval(atomSymbol("$LISH_TEST_UNSET"), repl_env))
+	assert.Equal(t, atomString("inner"), eval(read(`(with-env {"LISH_TEST" "inner"} (env "LISH_TEST"))`), repl_env))
+	assert.Equal(t, atomString("inner\n"), eval(read(`((with-env {"LISH_TEST" "inner"} (sh "-c" "echo $LISH_TEST")) "stdout")`), repl_env))
+	assert.Equal(t, atomString("outer"), eval(read(`(env "LISH_TEST")`), repl_env))
+}
+
+func TestWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	repl_env := newEnvRepl()
+	repl_env.set("dir", atomString(dir))
+	assert.Equal(t, atomString(dir), eval(read(`(with-dir dir (pwd))`), repl_env))
+	assert.Equal(t, atomString(dir+"\n"), eval(read(`((with-dir dir (sh "-c" "pwd")) "stdout")`), repl_env))
+	assert.Equal(t, atomString("/"), eval(read(`(with-dir dir (cd "/") (pwd))`), repl_env))
+	assert.Equal(t, atomString(dir), eval(read(`(with-dir dir (cd "/") (cd "-") (pwd))`), repl_env))
+	// relative file is opened in directory command is run in
+	eval(read(`(with-dir dir (with-io {:stdout (file "out.txt")} (sh "-c" "echo 1")))`), repl_env)
+	assert.Equal(t, atomString("1\n"), eval(read(`(with-dir dir (slurp "out.txt"))`), repl_env))
+
+	// cd changes directory of interpreter only
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.Equal(t, atomString(dir), eval(read(`(cd dir)`), repl_env))
+	assert.Equal(t, atomString(dir), eval(read(`(pwd)`), repl_env))
+	assert.Equal(t, atomString(dir), eval(read(`(env "PWD")`), repl_env))
+	assert.Equal(t, atomString(dir+"\n"), eval(read(`((sh "-c" "echo $PWD") :stdout)`), repl_env))
+	assert.Equal(t, atomString("1\n"), eval(read(`(slurp "out.txt")`), repl_env))
+	after, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.Equal(t, cwd, after)
+}
+
+func TestWithIO(t *testing.T) {
+	out := t.TempDir() + "/out.txt"
+	repl_env := newEnvRepl()
+	repl_env.set("out", atomString(out))
+	assert.Equal(t, atomString("a b\n"), eval(read(`((with-io {:stdin "a b\n"} (cat)) :stdout)`), repl_env))
+	assert.Equal(t, atomNil, eval(read(`((with-io {:stdout (file out)} (sh "-c" "echo 1")) :stdout)`), repl_env))
+	eval(read(`(with-io {:stdout (file out :append)} (sh "-c" "echo 2"))`), repl_env)
+	assert.Equal(t, atomString("1\n2\n"), eval(read(`(slurp out)`), repl_env))
+	assert.Equal(t, atomString("o\ne\n"), eval(read(`((with-io {:stderr :stdout} (sh "-c" "echo o; echo e >&2")) :stdout)`), repl_env))
+	assert.Equal(t, atomNil, eval(read(`((with-io {:stderr :null} (sh "-c" "echo e >&2")) :stderr)`), repl_env))
+}
+
+func TestTimeout(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"command":        {`(with-timeout 100 (sleep 5))`, lisherr("cancelled: timeout 100ms exceeded")},
+		"command_option": {`(sleep 5 {:timeout 100})`, lisherr("cancelled: timeout 100ms exceeded")},
+		"loop":           {`(with-timeout 100 (let (f (fn (n) (f (+ n 1)))) (f 0)))`, lisherr("cancelled: timeout 100ms exceeded")},
+		"nested":         {`(with-timeout 100 (with-timeout 10000 (sleep 5)))`, lisherr("cancelled: timeout 100ms exceeded")},
+		"done":           {`(with-timeout 10000 (+ 1 2))`, atomInt(3)},
+		"done_command":   {`((sh "-c" "echo ok" {:timeout 10000}) :stdout)`, atomString("ok\n")},
+		"cancelled":      {`(cancelled? (with-timeout 10 (sleep 5)))`, atomBool(true)},
+		"not_cancelled":  {`(cancelled? (throw "cancelled"))`, atomBool(false)},
+		"invalid":        {`(with-timeout 0 1)`, lisherr("with-timeout timeout must be positive int, not 0")},
+		"invalid_option": {`(sleep 5 {:retries 3})`, lisherr("unknown command option retries")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			assert.Equal(t, tc.res, eval(read(tc.input), newEnvRepl()))
+			assert.Less(t, time.Since(start), 3*time.Second)
+		})
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	repl_env := newEnvRepl()
+	assert.Equal(t, atomInt(2), eval(read(`(if (sh "-c" "exit 1") 1 2)`), repl_env))
+	assert.Equal(t, atomInt(1), eval(atomSymbol("$?"), repl_env))
+	assert.Equal(t, atomInt(1), eval(read(`(if (sh "-c" "exit 0") 1 2)`), repl_env))
+	assert.Equal(t, atomInt(0), eval(atomSymbol("$?"), repl_env))
+	assert.Equal(t, atomInt(3), eval(read(`(and 1 2 3)`), repl_env))
+	assert.Equal(t, atomBool(false), eval(read(`(and 1 false (throw "not evaluated"))`), repl_env))
+	assert.Equal(t, atomInt(2), eval(read(`(or false 2 (throw "not evaluated"))`), repl_env))
+	assert.Equal(t, atomBool(true), eval(read(`(and)`), repl_env))
+	assert.Equal(t, atomBool(false), eval(read(`(or)`), repl_env))
+	assert.Equal(t, atomString("fallback"), eval(read(`(or (sh "-c" "exit 2") "fallback")`), repl_env))
+}
@@ -0,0 +1,250 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+// loopTarget is bound to lambda of innermost loop, recur calls it. Name can
+// not be read, so that code can not shadow it.
+const loopTarget Symbol = " loop"
+
+// bindingPairs checks that bindings of loop form are pairs of symbol and
+// value form
+func bindingPairs(form string, bindings Atom) ([]Atom, Atom, bool) {
+	if bindings.Kind != AtomKindList {
+		return nil, lisherr("%s bindings must be list, not %s", form, bindings), false
+	}
+
+	pairs := bindings.Value.(List)
+	if len(pairs)%2 != 0 {
+		return nil, lisherr("%s requires even number of bindings, but got %d", form, len(pairs)), false
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if pairs[i].Kind != AtomKindSymbol {
+			return nil, lisherr("%s: %s is not a symbol", form, pairs[i]), false
+		}
+	}
+	return pairs, Atom{}, true
+}
+
+// evalLoop binds names of loop to initial values, returning loop body to be
+// evaluated in tail position along with environment of the first iteration
+func evalLoop(l List, env Env) (Atom, Env, bool) {
+	pairs, err, ok := bindingPairs("loop", l[1])
+	if !ok {
+		return err, Env{}, false
+	}
+
+	names := make([]Atom, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		names = append(names, pairs[i])
+	}
+	outer := env
+	frame := newEnv(fun.Valid(&outer))
+	body := atomList(append([]Atom{atomSymbol("progn")}, l[2:]...)...)
+	frame.set(loopTarget, atomLambda(Lambda{eval, body, frame, names, false, &Meta{name: "loop"}, arity{len(names), len(names)}, nil, new(byte)}))
+
+	// initial values see names bound before them, as in let
+	iteration := newEnv(fun.Valid(&frame))
+	for i := 0; i < len(pairs); i += 2 {
+		value := eval(pairs[i+1], iteration)
+		if value.Kind == AtomKindError {
+			return value, Env{}, false
+		}
+		iteration.set(pairs[i].Value.(Symbol), value)
+	}
+	return body, iteration, true
+}
+
+// evalRecur rebinds names of innermost loop to values of args, returning loop
+// body to be evaluated in tail position along with environment of the next
+// iteration
+func evalRecur(args []Atom, env Env) (Atom, Env, bool) {
+	target, ok := lookup(env, loopTarget)
+	if !ok {
+		return lisherr("recur called outside of loop"), Env{}, false
+	}
+
+	loop := target.Value.(Lambda)
+	if !loop.arity.accepts(len(args)) {
+		return lisherr("recur requires %s argument(s), but got %d", loop.arity, len(args)), Env{}, false
+	}
+	values := make([]Atom, len(args))
+	for i, arg := range args {
+		if values[i] = eval(arg, env); values[i].Kind == AtomKindError {
+			return values[i], Env{}, false
+		}
+	}
+	return newEnvCall(loop, env, values)
+}
+
+// evalIteration evaluates body of loop once, returning value of the last
+// form or first error. Cancellation and limits are checked before each
+// iteration, as calls in tail position check them.
+func evalIteration(body []Atom, env Env) Atom {
+	if cancelled, ok := env.scope.cancelled(); ok {
+		return cancelled
+	}
+	if exceeded, ok := env.scope.step(); ok {
+		return exceeded
+	}
+
+	res := atomNil
+	for _, form := range body {
+		if res = eval(form, env); res.Kind == AtomKindError {
+			return res
+		}
+	}
+	return res
+}
+
+// evalWhile evaluates body while test is truthy, returns nil
+func evalWhile(l List, env Env) Atom {
+
+	for {
+		test := eval(l[1], env)
+		if test.Kind == AtomKindError {
+			return test
+		}
+		if !truthy(test) {
+			return atomNil
+		}
+		if res := evalIteration(l[2:], env); res.Kind == AtomKindError {
+			return res
+		}
+	}
+}
+
+// evalDotimes evaluates body with name bound to 0, 1, ... n-1, returns nil
+func evalDotimes(l List, env Env) Atom {
+	spec, ok := l[1].Value.(List)
+	if !ok || len(spec) != 2 || spec[0].Kind != AtomKindSymbol {
+		return lisherr("dotimes requires (name n) binding, not %s", l[1])
+	}
+
+	n := eval(spec[1], env)
+	if n.Kind == AtomKindError {
+		return n
+	}
+	if n.Kind != AtomKindInt {
+		return lisherr("dotimes count must be int, not %s", n)
+	}
+
+	outer := env
+	for i := Int(0); i < n.Value.(Int); i++ {
+		iteration := newEnv(fun.Valid(&outer))
+		iteration.set(spec[0].Value.(Symbol), atomInt(int64(i)))
+		if res := evalIteration(l[2:], iteration); res.Kind == AtomKindError {
+			return res
+		}
+	}
+	return atomNil
+}
+
+// eachItem calls f with items of list, items of stream until it is closed, or
+// lines of string or stdout of successful command, until f returns false
+func eachItem(seq Atom, f func(Atom) bool) error {
+	switch seq.Kind {
+	case AtomKindList:
+		for _, item := range seq.Value.(List) {
+			if !f(item) {
+				return nil
+			}
+		}
+	case AtomKindStream:
+		for item := range seq.Value.(Stream) {
+			if !f(item) {
+				return nil
+			}
+		}
+	default:
+		if seq.Kind != AtomKindString && !isCommandResult(seq) {
+			return fmt.Errorf("%s is not list, stream, string or command result", seq)
+		}
+		text, err := inputText(seq)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			return nil
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			if !f(atomString(line)) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// evalFor returns list of values of body for each binding of names to items
+// of sequences, later sequences iterated first. :when clause skips bindings
+// its test is falsy for, :let clause binds names as let does.
+func evalFor(l List, env Env) Atom {
+	clauses, ok := l[1].Value.(List)
+	if !ok || len(clauses)%2 != 0 {
+		return lisherr("for requires list of clause pairs, not %s", l[1])
+	}
+
+	res := []Atom{}
+	var iterate func(clauses []Atom, env Env) Atom
+	iterate = func(clauses []Atom, env Env) Atom {
+		if len(clauses) == 0 {
+			value := evalIteration(l[2:], env)
+			if value.Kind != AtomKindError {
+				res = append(res, value)
+			}
+			return value
+		}
+
+		switch head, form := clauses[0], clauses[1]; {
+		case head == atomKeyword("when"):
+			test := eval(form, env)
+			if test.Kind == AtomKindError || !truthy(test) {
+				return test
+			}
+			return iterate(clauses[2:], env)
+		case head == atomKeyword("let"):
+			pairs, err, ok := bindingPairs("for :let", form)
+			if !ok {
+				return err
+			}
+			outer := env
+			scoped := newEnv(fun.Valid(&outer))
+			for i := 0; i < len(pairs); i += 2 {
+				value := eval(pairs[i+1], scoped)
+				if value.Kind == AtomKindError {
+					return value
+				}
+				scoped.set(pairs[i].Value.(Symbol), value)
+			}
+			return iterate(clauses[2:], scoped)
+		case head.Kind == AtomKindSymbol:
+			seq := eval(form, env)
+			if seq.Kind == AtomKindError {
+				return seq
+			}
+
+			outer, last := env, atomNil
+			if err := eachItem(seq, func(item Atom) bool {
+				scoped := newEnv(fun.Valid(&outer))
+				scoped.set(head.Value.(Symbol), item)
+				last = iterate(clauses[2:], scoped)
+				return last.Kind != AtomKindError
+			}); err != nil {
+				return lisherr("for: %s", err)
+			}
+			return last
+		default:
+			return lisherr("for: %s is not a symbol, :when or :let", head)
+		}
+	}
+
+	if last := iterate(clauses, env); last.Kind == AtomKindError {
+		return last
+	}
+	return atomList(res...)
+}
@@ -0,0 +1,45 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoops(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"loop":            {`(loop (i 0 acc ()) (if (< i 3) (recur (+ i 1) (cons i acc)) acc))`, atomList(atomInt(2), atomInt(1), atomInt(0))},
+		"loop_deep":       {`(loop (i 0) (if (< i 100000) (recur (+ i 1)) i))`, atomInt(100000)},
+		"loop_sequential": {`(loop (a 1 b (+ a 1)) (list a b))`, atomList(atomInt(1), atomInt(2))},
+		"loop_nested":     {`(loop (i 0 n 0) (if (< i 3) (recur (+ i 1) (+ n (loop (j 0) (if (< j 2) (recur (+ j 1)) j)))) n))`, atomInt(6)},
+		"loop_in_fn":      {`((fn (n) (loop (i n) (if (= i 0) :done (recur (- i 1))))) 10)`, atomKeyword("done")},
+		"recur_arity":     {`(loop (i 0) (recur 1 2))`, lisherr("recur requires 1 argument(s), but got 2")},
+		"recur_outside":   {`(recur 1)`, lisherr("recur called outside of loop")},
+		"recur_error":     {`(loop (i 0) (recur (throw "oops")))`, lisherr("oops")},
+		"loop_bindings":   {`(loop (1 2) 3)`, lisherr("loop: 1 is not a symbol")},
+		"while":           {`(let (c (chan 4)) (put! c 1) (put! c 2) (put! c false) (put! c :rest) (list (while (take! c)) (take! c)))`, atomList(atomNil, atomKeyword("rest"))},
+		"while_error":     {`(while true (throw "oops"))`, lisherr("oops")},
+		"while_timeout":   {`(with-timeout 100 (while true))`, lisherr("cancelled: timeout 100ms exceeded")},
+		"dotimes":         {`(let (c (chan 3)) (dotimes (i 3) (put! c i)) (list (take! c) (take! c) (take! c)))`, atomList(atomInt(0), atomInt(1), atomInt(2))},
+		"dotimes_zero":    {`(dotimes (i 0) (throw "not evaluated"))`, atomNil},
+		"dotimes_count":   {`(dotimes (i "a"))`, lisherr("dotimes count must be int, not a")},
+		"for":             {`(for (x '(1 2)) (* x 10))`, atomList(atomInt(10), atomInt(20))},
+		"for_nested":      {`(for (x '(1 2) y (list x 3)) (list x y))`, atomList(atomList(atomInt(1), atomInt(1)), atomList(atomInt(1), atomInt(3)), atomList(atomInt(2), atomInt(2)), atomList(atomInt(2), atomInt(3)))},
+		"for_when_let":    {`(for (x '(1 2 3) :when (not (= x 2)) :let (y (* x 10))) y)`, atomList(atomInt(10), atomInt(30))},
+		"for_empty":       {`(for (x ()) x)`, atomNil},
+		"for_lines":       {`(for (line "a\nb\n") (join line "!"))`, atomList(atomString("a!"), atomString("b!"))},
+		"for_command":     {`(for (line (sh "-c" "echo a; echo b")) line)`, atomList(atomString("a"), atomString("b"))},
+		"for_stream":      {`(let (c (chan 2)) (put! c 1) (put! c 2) (close! c) (for (x c) x))`, atomList(atomInt(1), atomInt(2))},
+		"for_error":       {`(for (x '(1 2)) (throw "oops"))`, lisherr("oops")},
+		"for_seq":         {`(for (x 1) x)`, lisherr("for: 1 is not list, stream, string or command result")},
+		"for_clause":      {`(for (1 2) 3)`, lisherr("for: 1 is not a symbol, :when or :let")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(readValue(tc.input), newEnvRepl()))
+			assert.Equal(t, tc.res, evalCompiled(readValue(tc.input), newEnvRepl()))
+		})
+	}
+}
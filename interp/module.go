@@ -0,0 +1,348 @@
+package interp
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// moduleTable caches modules by absolute path of their file, so each module
+// is evaluated once
+type moduleTable struct {
+	mu      sync.Mutex
+	loaded  map[string]Atom        // path -> hash of exported names
+	loading map[string]*moduleLoad // path -> module being evaluated
+}
+
+// moduleLoad is evaluation of module, requirers of the same module wait for
+// it instead of evaluating module again
+type moduleLoad struct {
+	done    chan struct{} // closed once exports are set
+	exports Atom
+	// module this one waits to be loaded, empty if none. Waits of loads form
+	// chains which tell cycles of requires made by different goroutines.
+	waits string
+}
+
+func newModuleTable() *moduleTable {
+	return &moduleTable{loaded: map[string]Atom{}, loading: map[string]*moduleLoad{}}
+}
+
+// moduleTable returns modules required in scope, modules required without
+// interpreter are not cached
+func (s *shellScope) moduleTable() *moduleTable {
+	if s == nil || s.modules == nil {
+		return newModuleTable()
+	}
+	return s.modules
+}
+
+// cycle returns cycle of requires which loading path by evaluation of modules
+// chain would close, nil if there is none. Besides the chain itself, path
+// might be waited for by modules being loaded by others, which wait for
+// modules in chain.
+func (t *moduleTable) cycle(chain []string, path string) []string {
+	seq := []string{path}
+	for cur := path; len(seq) <= len(t.loading)+1; {
+		if i := slices.Index(chain, cur); i != -1 {
+			return append(slices.Clone(chain[i:]), seq...)
+		}
+		load, ok := t.loading[cur]
+		if !ok || load.waits == "" {
+			return nil
+		}
+		cur = load.waits
+		seq = append(seq, cur)
+	}
+	return nil
+}
+
+// evalFile evaluates forms of file one by one in env, returning last result
+func evalFile(path string, env Env) Atom {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return lisherr("%s", err)
+	}
+	return evalProgram(stripShebang(string(b)), env)
+}
+
+// stripShebang blanks out #! line at the start of script, keeping line
+// numbers of the rest
+func stripShebang(src string) string {
+	if !strings.HasPrefix(src, "#!") {
+		return src
+	}
+	if i := strings.IndexByte(src, '\n'); i != -1 {
+		return src[i:]
+	}
+	return ""
+}
+
+// evalProgram evaluates forms of src one by one in env, returning last result
+func evalProgram(src string, env Env) Atom {
+	forms := read("(progn " + src + "\n)")
+	if forms.Kind == AtomKindError {
+		return forms
+	}
+
+	res := atomNil
+	for _, form := range forms.Value.(List)[1:] {
+		env.scope.warn(matchWarnings(form, env))
+		if res = evalForm(form, env); res.Kind == AtomKindError {
+			return res
+		}
+	}
+	return res
+}
+
+// builtinLoadFile evaluates file in root environment, redefining everything
+// it sets each time it is loaded. *FILE* is bound to file path while loading.
+func builtinLoadFile(env Env, args ...Atom) Atom {
+	path, err := env.scope.readPath(string(args[0].Value.(String)))
+	if err != nil {
+		return lisherr("%s", err)
+	}
+
+	root := env.root()
+	prevFile, hadFile := root.local("*FILE*")
+	root.set("*FILE*", atomString(path))
+	defer func() {
+		if hadFile {
+			root.set("*FILE*", prevFile)
+		} else {
+			root.unset("*FILE*")
+		}
+	}()
+
+	return evalFile(path, root)
+}
+
+// moduleDir returns directory modules are required relative to: directory of
+// file being loaded or current directory
+func moduleDir(env Env) (string, error) {
+	if file, ok := env.get("*FILE*"); ok && file.Kind == AtomKindString {
+		return filepath.Dir(string(file.Value.(String))), nil
+	}
+	return env.scope.getwd()
+}
+
+// resolveModule finds file of module. Names starting with ./ or ../ are
+// relative to requiring file, others are searched in its directory and then
+// in LISH_PATH directories. Extension .lish might be omitted.
+func resolveModule(env Env, name string) (string, error) {
+	base, err := moduleDir(env)
+	if err != nil {
+		return "", err
+	}
+
+	dirs := []string{base}
+	switch {
+	case filepath.IsAbs(name):
+		dirs = []string{""}
+	case strings.HasPrefix(name, "./"), strings.HasPrefix(name, "../"):
+	default:
+		if lishPath, ok := env.scope.getenv("LISH_PATH"); ok {
+			for _, dir := range filepath.SplitList(lishPath) {
+				if dir, err := env.scope.resolvePath(dir); err == nil && dir != "" {
+					dirs = append(dirs, dir)
+				}
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, candidate := range []string{name + ".lish", name} {
+			path := filepath.Join(dir, candidate)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return env.scope.readPath(path)
+			}
+		}
+	}
+	return "", fmt.Errorf("module %s not found in %s", name, strings.Join(dirs, string(filepath.ListSeparator)))
+}
+
+// newEnvModule makes environment module is evaluated in, it has only builtins
+// defined
+func newEnvModule(scope *shellScope, path string) Env {
+	env := newEnvRoot(maps.Clone(namespace), scope)
+	env.set("*FILE*", atomString(path))
+	return env
+}
+
+// moduleExports returns hash of names module provides, or of all names it
+// defines if it has no provide list
+func moduleExports(path string, env Env) Atom {
+	res := map[string]Atom{}
+	if provided, ok := env.local("*EXPORTS*"); ok {
+		for _, name := range provided.Value.(List) {
+			value, ok := env.local(name.Value.(Symbol))
+			if !ok {
+				return lisherr("module %s provides %s, but does not define it", path, name)
+			}
+			res[name.String()] = value
+		}
+		return atomHash(res)
+	}
+
+	for name, value := range env.bindings() {
+		// modules required by module are not its exports
+		if name == "*FILE*" || name == "Value" && kindName(value) == "protocol" || strings.HasPrefix(string(name), " module ") {
+			continue
+		}
+		if builtin, ok := namespace[name]; ok && builtin.Kind == value.Kind {
+			continue
+		}
+		res[string(name)] = value
+	}
+	return atomHash(res)
+}
+
+// loadModule evaluates module from file once, returning hash of its exports.
+// Module required while it is loaded by other goroutine is waited for.
+func loadModule(scope *shellScope, path string) Atom {
+	chain := scope.modulesLoading()
+	modules := scope.moduleTable()
+	modules.mu.Lock()
+	if exports, ok := modules.loaded[path]; ok {
+		modules.mu.Unlock()
+		return exports
+	}
+
+	if cycle := modules.cycle(chain, path); cycle != nil {
+		modules.mu.Unlock()
+		return lisherr("require cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	if len(chain) > 0 {
+		requirer := modules.loading[chain[len(chain)-1]]
+		requirer.waits = path
+		defer func() {
+			modules.mu.Lock()
+			defer modules.mu.Unlock()
+			requirer.waits = ""
+		}()
+	}
+
+	if load, ok := modules.loading[path]; ok {
+		modules.mu.Unlock()
+		select {
+		case <-load.done:
+			return load.exports
+		case <-scope.context().Done():
+			res, _ := scope.cancelled()
+			return res
+		}
+	}
+
+	load := &moduleLoad{done: make(chan struct{})}
+	modules.loading[path] = load
+	modules.mu.Unlock()
+
+	// lock is not held while module is evaluated, as it might require others
+	env := newEnvModule(scope.withModuleLoading(path), path)
+	exports := evalFile(path, env)
+	if exports.Kind == AtomKindError {
+		exports = lisherr("require %s: %s", path, string(exports.Value.(Error)))
+	} else {
+		exports = moduleExports(path, env)
+	}
+
+	modules.mu.Lock()
+	delete(modules.loading, path)
+	if exports.Kind != AtomKindError {
+		modules.loaded[path] = exports
+	}
+	load.exports = exports
+	modules.mu.Unlock()
+	close(load.done)
+	return exports
+}
+
+// moduleAlias is name hash of module exports is bound to. Aliases are kept
+// apart from ordinary names, so that module named like builtin does not
+// shadow it.
+func moduleAlias(alias string) Symbol {
+	return Symbol(" module " + alias)
+}
+
+// builtinRequire loads module and binds hash of its exports to module name,
+// or to alias given as (require "name" :as alias), in module table of env.
+// Exported names are accessed as name/export, hash is returned too.
+func builtinRequire(env Env, args ...Atom) Atom {
+	var name string
+	switch args[0].Kind {
+	case AtomKindString:
+		name = string(args[0].Value.(String))
+	case AtomKindSymbol:
+		name = string(args[0].Value.(Symbol))
+	}
+
+	alias := strings.TrimSuffix(filepath.Base(name), ".lish")
+	switch opts := args[1:]; {
+	case len(opts) == 0:
+	case len(opts) == 2 && opts[0] == atomKeyword("as") && opts[1].Kind == AtomKindSymbol:
+		alias = string(opts[1].Value.(Symbol))
+	case len(opts) == 2 && opts[0] == atomKeyword("as") && opts[1].Kind == AtomKindString:
+		// unbound alias symbol is evaluated to string
+		alias = string(opts[1].Value.(String))
+	default:
+		return lisherr("unknown require options %s", atomList(opts...))
+	}
+
+	path, err := resolveModule(env, name)
+	if err != nil {
+		return lisherr("%s", err)
+	}
+
+	exports := loadModule(env.scope, path)
+	if exports.Kind == AtomKindError {
+		return exports
+	}
+
+	env.set(moduleAlias(alias), exports)
+	return exports
+}
+
+// lookupQualified finds symbol like str/split as split in exports of module
+// required as str, or in hash bound to str
+func lookupQualified(env Env, s Symbol) (Atom, bool) {
+	prefix, name, ok := strings.Cut(string(s), "/")
+	if !ok || prefix == "" || name == "" {
+		return Atom{}, false
+	}
+
+	module, ok := env.get(moduleAlias(prefix))
+	if !ok {
+		module, ok = env.get(Symbol(prefix))
+	}
+	if !ok || module.Kind != AtomKindHash {
+		return Atom{}, false
+	}
+
+	for {
+		key, rest, nested := strings.Cut(name, "/")
+		value, ok := module.Value.(Hash)[key]
+		if !ok {
+			return Atom{}, false
+		}
+		if !nested {
+			return value, true
+		}
+		if value.Kind != AtomKindHash {
+			return Atom{}, false
+		}
+		module, name = value, rest
+	}
+}
+
+// lookup finds value bound to symbol, including qualified ones
+func lookup(env Env, s Symbol) (Atom, bool) {
+	if a, ok := env.get(s); ok {
+		return a, true
+	}
+	return lookupQualified(env, s)
+}
@@ -0,0 +1,103 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestRequire(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/str.lish": `
+			(provide split)
+			(setenv "LISH_TEST_LOADS" (join (env "LISH_TEST_LOADS") "+"))
+			(set split (fn (s) (list s s)))
+			(set helper 1)`,
+		"path/util.lish": `(set twice (fn (x) (* 2 x)))`,
+		"main.lish": `
+			(require "lib/str")
+			(require "./lib/str" :as s)
+			(require 'util)
+			(list (str/split "x") (s/split "y") (util/twice 21) helper (str 1 2))`,
+	})
+	t.Setenv("LISH_TEST_LOADS", "")
+	t.Setenv("LISH_PATH", filepath.Join(dir, "path"))
+
+	repl_env := newEnvRepl()
+	repl_env.set("main", atomString(filepath.Join(dir, "main.lish")))
+	assert.Equal(t, atomList(
+		atomList(atomString("x"), atomString("x")),
+		atomList(atomString("y"), atomString("y")),
+		atomInt(42),
+		atomString("helper"),
+		// module alias does not shadow builtin
+		atomString("12"),
+	), eval(read(`(load-file main)`), repl_env))
+	// module is evaluated once
+	assert.Equal(t, atomString("+"), eval(read(`(env "LISH_TEST_LOADS")`), repl_env))
+}
+
+func TestRequireCycle(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.lish": `(require "./b")`,
+		"b.lish": `(require "./a")`,
+	})
+	a, b := filepath.Join(dir, "a.lish"), filepath.Join(dir, "b.lish")
+
+	repl_env := newEnvRepl()
+	repl_env.set("a", atomString(a))
+	assert.Equal(t,
+		lisherr("require %s: require %s: require cycle: %s -> %s -> %s", a, b, a, b, a),
+		eval(read(`(require a)`), repl_env),
+	)
+}
+
+func TestLoadFileShebang(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"script.lish": "#!/usr/bin/env lish\n(list 1 '#!x)\n",
+		"empty.lish":  "#!/usr/bin/env lish",
+	})
+
+	repl_env := newEnvRepl()
+	repl_env.set("dir", atomString(dir))
+	// only the first line is shebang
+	assert.Equal(t, atomList(atomInt(1), atomSymbol("#!x")), eval(read(`(load-file (str dir "/script.lish"))`), repl_env))
+	assert.Equal(t, atomNil, eval(read(`(load-file (str dir "/empty.lish"))`), repl_env))
+	// error text is not format string
+	assert.Equal(t,
+		lisherr("open %s/100%%.lish: no such file or directory", dir),
+		eval(read(`(load-file (str dir "/100%.lish"))`), repl_env),
+	)
+}
+
+func TestRequireConcurrent(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"slow.lish": `(dotimes (i 100000) i) (set x 1)`,
+		"a.lish":    `(dotimes (i 100000) i) (require "./b")`,
+		"b.lish":    `(dotimes (i 100000) i) (require "./a")`,
+	})
+
+	repl_env := newEnvRepl()
+	repl_env.set("dir", atomString(dir))
+	// requirers wait for module being loaded instead of seeing a cycle
+	assert.Equal(t,
+		atomList(atomInt(1), atomInt(1), atomInt(1), atomInt(1)),
+		eval(read(`(pmap (fn (i) ((require (str dir "/slow")) :x)) '(1 2 3 4) 4)`), repl_env),
+	)
+	// cycle through modules loaded by different goroutines is still found
+	res := eval(read(`(pmap (fn (m) (require (str dir m))) '("/a" "/b") 2)`), repl_env)
+	assert.Equal(t, AtomKindError, res.Kind)
+	assert.Contains(t, string(res.Value.(Error)), "require cycle")
+}
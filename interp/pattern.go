@@ -0,0 +1,359 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rprtr258/fun"
+)
+
+// absent is value of missing list item or hash key, it matches only name with
+// default value
+var absent = Atom{}
+
+// compositePattern is list or hash pattern split into its parts
+type compositePattern struct {
+	// items of list pattern, values of hash pattern by their keys
+	items []Atom
+	keys  []string
+	// pattern rest of list items are matched against
+	rest fun.Option[Atom]
+	// names after &key, bound to values following keywords of the same name
+	// in rest of list items
+	named []Symbol
+	// name whole value is bound to
+	as fun.Option[Symbol]
+	// default values of names by their names
+	defaults Hash
+}
+
+// parseComposite splits list pattern
+// (p ... & rest &key name ... :as name :or {:name default}), hash pattern
+// {key p ...} or hash pattern with options
+// (&hash {key p ...} :as name :or {:name default}) into its parts. Keys of
+// hash are never options, as string and keyword keys are the same.
+func parseComposite(pattern Atom) (compositePattern, error) {
+	res := compositePattern{}
+	// values after :as and :or
+	var as, defaults fun.Option[Atom]
+	l, isList := pattern.Value.(List)
+	h, isHash := hashPattern(pattern)
+	if isHash {
+		for k := range h {
+			res.keys = append(res.keys, k)
+		}
+		// names are bound in the same order every time
+		slices.Sort(res.keys)
+		for _, k := range res.keys {
+			res.items = append(res.items, h[k])
+		}
+		if !isList {
+			return res, nil
+		}
+		// options follow hash
+		l = l[2:]
+	}
+
+	for i := 0; i < len(l); i++ {
+		switch {
+		case l[i] == atomKeyword("as") || l[i] == atomKeyword("or"):
+			if i+1 == len(l) {
+				return res, fmt.Errorf("%s must be followed by value in %s", l[i], pattern)
+			}
+			if l[i] == atomKeyword("as") {
+				as = fun.Valid(l[i+1])
+			} else {
+				defaults = fun.Valid(l[i+1])
+			}
+			i++
+		case isHash:
+			return res, fmt.Errorf("only :as and :or might follow hash in %s", pattern)
+		case l[i] == atomSymbol("&"):
+			if i+1 == len(l) {
+				return res, fmt.Errorf("& must be followed by pattern in %s", pattern)
+			}
+			res.rest = fun.Valid(l[i+1])
+			i++
+		case l[i] == atomSymbol("&key"):
+			for i+1 < len(l) && l[i+1].Kind == AtomKindSymbol {
+				res.named = append(res.named, l[i+1].Value.(Symbol))
+				i++
+			}
+			if len(res.named) == 0 {
+				return res, fmt.Errorf("&key must be followed by names in %s", pattern)
+			}
+		case res.rest.Valid || len(res.named) > 0:
+			return res, fmt.Errorf("pattern after rest ones in %s", pattern)
+		default:
+			res.items = append(res.items, l[i])
+		}
+	}
+
+	if as.Valid {
+		if as.Value.Kind != AtomKindSymbol {
+			return res, fmt.Errorf(":as must be followed by name, not %s", as.Value)
+		}
+		res.as = fun.Valid(as.Value.Value.(Symbol))
+	}
+	if defaults.Valid {
+		if defaults.Value.Kind != AtomKindHash {
+			return res, fmt.Errorf(":or must be followed by hash of default values, not %s", defaults.Value)
+		}
+		res.defaults = defaults.Value.Value.(Hash)
+	}
+	return res, nil
+}
+
+// hashPattern returns hash of patterns by keys of hash pattern, which might be
+// wrapped into (&hash ...) to be given options
+func hashPattern(pattern Atom) (Hash, bool) {
+	switch v := pattern.Value.(type) {
+	case Hash:
+		return v, true
+	case List:
+		if len(v) >= 2 && v[0] == atomSymbol("&hash") && v[1].Kind == AtomKindHash {
+			return v[1].Value.(Hash), true
+		}
+	}
+	return nil, false
+}
+
+// isLiteral reports whether pattern matches only value equal to it
+func isLiteral(pattern Atom) bool {
+	switch pattern.Kind {
+	case AtomKindBool, AtomKindInt, AtomKindFloat, AtomKindString, AtomKindKeyword:
+		return true
+	case AtomKindList:
+		l := pattern.Value.(List)
+		return len(l) == 0 || len(l) == 2 && l[0] == atomSymbol("quote")
+	default:
+		return false
+	}
+}
+
+// bindPattern binds names of pattern to parts of value in env, reporting
+// whether value matches pattern. Patterns are:
+//   - _ matching anything
+//   - name bound to value
+//   - literal, nil or quoted form, matching value equal to it
+//   - list of patterns matching list items one by one, rest of items are
+//     matched against pattern after & or are keywords followed by values of
+//     names after &key, which are nil if not given
+//   - hash of patterns matching values of the same keys, which is wrapped
+//     into (&hash {key pattern ...} & options) to be given options
+//
+// List and hash patterns might bind whole value to name after :as option. Names of
+// items they miss are bound to default values from hash after :or, which are
+// evaluated in env. Error is returned if pattern is invalid or default value
+// fails.
+func bindPattern(pattern, value Atom, env Env) (bool, Atom) {
+	switch {
+	case pattern == atomSymbol("_"):
+		return value != absent, Atom{}
+	case pattern.Kind == AtomKindSymbol:
+		if value == absent {
+			return false, Atom{}
+		}
+		env.set(pattern.Value.(Symbol), value)
+		return true, Atom{}
+	case isLiteral(pattern):
+		if l, ok := pattern.Value.(List); ok && len(l) == 2 {
+			pattern = l[1]
+		}
+		return value != absent && atomEq(pattern, value), Atom{}
+	case pattern.Kind != AtomKindList && pattern.Kind != AtomKindHash:
+		return false, lisherr("%s is not a pattern", pattern)
+	}
+
+	parts, err := parseComposite(pattern)
+	if err != nil {
+		return false, lisherr("invalid pattern: %s", err)
+	}
+	if _, isHash := hashPattern(pattern); value.Kind != fun.IF(isHash, AtomKindHash, pattern.Kind) {
+		return false, Atom{}
+	}
+
+	// item is value of i-th item pattern, absent if value misses it
+	item := func(i int) Atom {
+		switch v := value.Value.(type) {
+		case List:
+			if i < len(v) {
+				return v[i]
+			}
+		case Hash:
+			if res, ok := v[parts.keys[i]]; ok {
+				return res
+			}
+		}
+		return absent
+	}
+	if l, ok := value.Value.(List); ok && !parts.rest.Valid && len(parts.named) == 0 && len(l) > len(parts.items) {
+		return false, Atom{}
+	}
+	// defaultValue evaluates default value of name, absent if it has none
+	defaultValue := func(name Symbol) Atom {
+		if def, ok := parts.defaults[string(name)]; ok {
+			return eval(def, env)
+		}
+		return absent
+	}
+
+	for i, p := range parts.items {
+		v := item(i)
+		if v == absent && p.Kind == AtomKindSymbol {
+			if v = defaultValue(p.Value.(Symbol)); v.Kind == AtomKindError {
+				return false, v
+			}
+		}
+		if ok, err := bindPattern(p, v, env); !ok {
+			return false, err
+		}
+	}
+	rest := List{}
+	if l, ok := value.Value.(List); ok && len(l) > len(parts.items) {
+		rest = l[len(parts.items):]
+	}
+	if parts.rest.Valid {
+		if ok, err := bindPattern(parts.rest.Value, atomList(rest...), env); !ok {
+			return false, err
+		}
+	}
+	if len(parts.named) > 0 {
+		// rest of items are keywords followed by values of names
+		if len(rest)%2 != 0 {
+			return false, Atom{}
+		}
+		values := make(map[Symbol]Atom, len(rest)/2)
+		for i := 0; i < len(rest); i += 2 {
+			k, ok := rest[i].Value.(Keyword)
+			if !ok || !slices.Contains(parts.named, Symbol(k)) {
+				return false, Atom{}
+			}
+			values[Symbol(k)] = rest[i+1]
+		}
+		for _, name := range parts.named {
+			v, ok := values[name]
+			if !ok {
+				// named values are optional
+				if v = defaultValue(name); v == absent {
+					v = atomNil
+				} else if v.Kind == AtomKindError {
+					return false, v
+				}
+			}
+			env.set(name, v)
+		}
+	}
+	if parts.as.Valid {
+		env.set(parts.as.Value, value)
+	}
+	return true, Atom{}
+}
+
+// irrefutable reports whether pattern matches any value
+func irrefutable(pattern Atom) bool {
+	return pattern.Kind == AtomKindSymbol && pattern != atomSymbol("&")
+}
+
+// matchClause is clause of match form
+type matchClause struct {
+	pattern Atom
+	guard   fun.Option[Atom]
+	body    []Atom
+}
+
+// parseMatchClauses splits clauses of match form into patterns, guards after
+// :when and bodies
+func parseMatchClauses(l []Atom) ([]matchClause, error) {
+	clauses := make([]matchClause, len(l))
+	for i, clause := range l {
+		c, ok := clause.Value.(List)
+		if !ok || len(c) == 0 {
+			return nil, fmt.Errorf("match clause must be (pattern & body), not %s", clause)
+		}
+
+		clauses[i] = matchClause{c[0], fun.Invalid[Atom](), c[1:]}
+		if len(c) >= 3 && c[1] == atomKeyword("when") {
+			clauses[i].guard, clauses[i].body = fun.Valid(c[2]), c[3:]
+		}
+	}
+	return clauses, nil
+}
+
+// matchWarnings returns warnings about match forms in form without clause
+// matching any value. Forms are checked once they are read, so match written
+// in macro template is checked along with macro instead of each expansion.
+func matchWarnings(form Atom, env Env) []string {
+	l, ok := form.Value.(List)
+	if !ok || len(l) == 0 || l[0] == atomSymbol("quote") {
+		return nil
+	}
+
+	res := []string{}
+	if l[0] == atomSymbol("match") && len(l) >= 2 && !matchExhaustive(l[2:]) {
+		pos := sourcePos(env, form)
+		if pos != "" {
+			pos += ": "
+		}
+		res = append(res, fmt.Sprintf("%smatch on %s is not exhaustive, add _ clause", pos, l[1]))
+	}
+	for _, item := range l {
+		res = append(res, matchWarnings(item, env)...)
+	}
+	return res
+}
+
+// matchExhaustive reports whether some of match clauses matches any value.
+// Invalid clauses are reported once match is evaluated instead.
+func matchExhaustive(l []Atom) bool {
+	clauses, err := parseMatchClauses(l)
+	if err != nil {
+		return true
+	}
+	for _, clause := range clauses {
+		if irrefutable(clause.pattern) && !clause.guard.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// evalMatch returns body of first clause whose pattern value of expr matches
+// and guard after :when is truthy for, to be evaluated in tail position along
+// with environment pattern names are bound in
+func evalMatch(ast Atom, env Env) (Atom, Env, bool) {
+	l := ast.Value.(List)
+	clauses, err := parseMatchClauses(l[2:])
+	if err != nil {
+		return lisherr("%s", err), Env{}, false
+	}
+
+	value := eval(l[1], env)
+	if value.Kind == AtomKindError {
+		return value, Env{}, false
+	}
+	for _, clause := range clauses {
+		outer := env
+		clauseEnv := newEnv(fun.Valid(&outer))
+		matched, bindErr := bindPattern(clause.pattern, value, clauseEnv)
+		if bindErr.Kind == AtomKindError {
+			return bindErr, Env{}, false
+		}
+		if !matched {
+			continue
+		}
+		if clause.guard.Valid {
+			test := eval(clause.guard.Value, clauseEnv)
+			if test.Kind == AtomKindError {
+				return test, Env{}, false
+			}
+			if !truthy(test) {
+				continue
+			}
+		}
+
+		last, ok := evalBody(clause.body, clauseEnv)
+		return last, clauseEnv, ok
+	}
+	return lisherr("no match clause matched %s", value), Env{}, false
+}
@@ -0,0 +1,104 @@
+package interp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestructuring(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"list":          {`(let ((a b) (list 1 2)) (+ a b))`, atomInt(3)},
+		"rest":          {`(let ((a & xs) (list 1 2 3)) xs)`, atomList(atomInt(2), atomInt(3))},
+		"rest_empty":    {`(let ((a & xs) (list 1)) xs)`, atomNil},
+		"nested":        {`(let ((a (b c)) (list 1 (list 2 3))) (list a b c))`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"wildcard":      {`(let ((_ b) (list 1 2)) b)`, atomInt(2)},
+		"as":            {`(let ((a & _ :as all) (list 1 2)) (list a all))`, atomList(atomInt(1), atomList(atomInt(1), atomInt(2)))},
+		"list_default":  {`(let ((a b :or {:b (+ a 1)}) (list 1)) (list a b))`, atomList(atomInt(1), atomInt(2))},
+		"hash":          {`(let ({:a a :b b} {:a 1 :b 2 :c 3}) (list a b))`, atomList(atomInt(1), atomInt(2))},
+		"hash_default":  {`(let ((&hash {:a a :b b} :or {:b 5}) {:a 1}) (list a b))`, atomList(atomInt(1), atomInt(5))},
+		"hash_as":       {`(let ((&hash {:a a} :as h) {:a 1}) h)`, atomHash(map[string]Atom{"a": atomInt(1)})},
+		"hash_option":   {`(let ({"as" x :or y} {:as 1 "or" 2}) (list x y))`, atomList(atomInt(1), atomInt(2))},
+		"hash_invalid":  {`(let ((&hash {:a a} b) {:a 1}) a)`, lisherr("invalid pattern: only :as and :or might follow hash in (&hash {\"a\" a} b)")},
+		"command":       {`(let ({:stdout out :exit_code 0} (sh "-c" "echo hi")) out)`, atomString("hi\n")},
+		"literal":       {`(let ((:ok x) (list :ok 1)) x)`, atomInt(1)},
+		"too_few":       {`(let ((a b) (list 1)) a)`, lisherr("(1) does not match pattern (a b)")},
+		"too_many":      {`(let ((a) (list 1 2)) a)`, lisherr("(1 2) does not match pattern (a)")},
+		"missing_key":   {`(let ({:a a} {:b 1}) a)`, lisherr(`{"b" 1} does not match pattern {"a" a}`)},
+		"kind":          {`(let ((a) 1) a)`, lisherr("1 does not match pattern (a)")},
+		"default_error": {`(let ((a :or {:a (throw "oops")}) ()) a)`, lisherr("oops")},
+		"invalid":       {`(let ((a :as 1) (list 1)) a)`, lisherr("invalid pattern: :as must be followed by name, not 1")},
+		"fn":            {`((fn ((a b) {:k k}) (list a b k)) (list 1 2) {:k 3})`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"fn_rest":       {`((fn (a & (b c)) (list a b c)) 1 2 3)`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"fn_mismatch":   {`((fn ((a b)) a) (list 1))`, lisherr("fn: arguments ((1)) do not match params ((a b))")},
+		"defun": {`(progn
+			(setmacro defun (fn (f args & body) ` + "`" + `(set ,f (fn ,args ,@body))))
+			(defun swap ((a b)) (list b a))
+			(swap (list 1 2)))`, atomList(atomInt(2), atomInt(1))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(readValue(tc.input), newEnvRepl()))
+			assert.Equal(t, tc.res, evalCompiled(readValue(tc.input), newEnvRepl()))
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input   string
+		res     Atom
+		warning string
+	}{
+		"literal":   {`(match 2 (1 :one) (2 :two) (_ :many))`, atomKeyword("two"), ""},
+		"wildcard":  {`(match 3 (1 :one) (_ :many))`, atomKeyword("many"), ""},
+		"name":      {`(match 3 (1 :one) (n (* n 10)))`, atomInt(30), ""},
+		"nil":       {`(match () (() :empty) (_ :other))`, atomKeyword("empty"), ""},
+		"quoted":    {`(match 'b ('a 1) ('b 2) (_ 3))`, atomInt(2), ""},
+		"list":      {`(match (list 1 2) ((a) a) ((a b) (+ a b)) (_ 0))`, atomInt(3), ""},
+		"nested":    {`(match (list :add (list 1 2)) ((:add (a b)) (+ a b)) (_ 0))`, atomInt(3), ""},
+		"hash":      {`(match {:kind :circle :r 2} ({:kind :square :side s} s) ({:kind :circle :r r} (* r r)) (_ 0))`, atomInt(4), ""},
+		"guard":     {`(match 5 (n :when (< n 0) :neg) (n :when (> n 0) :pos) (_ :zero))`, atomKeyword("pos"), ""},
+		"guard_let": {`(match (list 1 2) ((a b) :when (> a b) :desc) ((a b) :asc) (_ :other))`, atomKeyword("asc"), ""},
+		"body":      {`(match 1 (x (+ x 1) (+ x 2)))`, atomInt(3), ""},
+		"scope":     {`(let (x 1) (match 2 ((x) x) (_ x)))`, atomInt(1), ""},
+		"no_match":  {`(match 3 (1 :one) (2 :two))`, lisherr("no match clause matched 3"), "warning: match on 3 is not exhaustive, add _ clause\n"},
+		"guarded":   {`(match 3 (n :when (> n 5) :big))`, lisherr("no match clause matched 3"), "warning: match on 3 is not exhaustive, add _ clause\n"},
+		"matched":   {`(match 1 (1 :one))`, atomKeyword("one"), "warning: match on 1 is not exhaustive, add _ clause\n"},
+		"error":     {`(match (throw "oops") (_ 1))`, lisherr("oops"), ""},
+		"clause":    {`(match 1 1)`, lisherr("match clause must be (pattern & body), not 1"), ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			env := newEnvRepl()
+			env.scope.warnings = &buf
+
+			assert.Equal(t, tc.res, evalProgram(tc.input, env))
+			assert.Equal(t, tc.warning, buf.String())
+		})
+	}
+}
+
+func TestMatchWarnsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	env := newEnvRepl()
+	env.scope.warnings = &buf
+
+	assert.Equal(t, atomList(atomKeyword("one"), atomKeyword("one")), evalProgram(`(for (x '(1 1)) (match x (1 :one)))`, env))
+	assert.Equal(t, "warning: match on x is not exhaustive, add _ clause\n", buf.String())
+
+	// match in macro template is checked along with macro, not each expansion
+	buf.Reset()
+	assert.Equal(t, atomList(atomKeyword("one"), atomKeyword("one")), evalProgram(`
+		(setmacro one? (fn (x) `+"`"+`(match ,x (1 :one))))
+		(list (one? 1) (one? 1))`, env))
+	assert.Equal(t, "warning: match on (unquote x) is not exhaustive, add _ clause\n", buf.String())
+
+	// clauses spliced into template are not known until expansion
+	buf.Reset()
+	evalProgram(`(setmacro m (fn (x & clauses) `+"`"+`(match ,x ,@clauses)))`, env)
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,216 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+	"github.com/rprtr258/fun"
+)
+
+const (
+	// pprintMaxString is number of runes of string pprint shows
+	pprintMaxString = 1000
+	// pprintMaxItems is number of list elements or hash entries pprint shows
+	pprintMaxItems = 100
+)
+
+// printer lays out atoms readably, so that they are read back to equal atoms.
+// Hash keys are sorted so output is stable.
+type printer struct {
+	sb       strings.Builder
+	width    int                  // max line width, 0 means single line
+	truncate bool                 // cut huge strings, lists and hashes
+	visiting map[uintptr]struct{} // lists and hashes being printed
+}
+
+// prStr returns readable representation of a on single line
+func prStr(a Atom) string {
+	p := printer{}
+	p.write(a, 0)
+	return p.sb.String()
+}
+
+// pprint returns readable representation of a laid out to fit in width,
+// huge values are truncated
+func pprint(a Atom, width int) string {
+	p := printer{width: width, truncate: true}
+	p.write(a, 0)
+	return p.sb.String()
+}
+
+// screenWidth returns terminal width, 80 if stdout is not a terminal
+func screenWidth() int {
+	if w := readline.GetScreenWidth(); w > 0 {
+		return w
+	}
+	return 80
+}
+
+func formatFloat(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	case math.IsNaN(x):
+		return "NaN"
+	}
+
+	s := strconv.FormatFloat(x, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		// keep it float when read back
+		s += ".0"
+	}
+	return s
+}
+
+// identity returns address of list or hash contents, used to detect cycles
+func identity(a Atom) (uintptr, bool) {
+	switch a.Kind {
+	case AtomKindList, AtomKindHash:
+		if v := reflect.ValueOf(a.Value); v.Len() > 0 {
+			return v.Pointer(), true
+		}
+	}
+	return 0, false
+}
+
+// items returns elements of list or keys and values of hash, sorted by key
+func items(a Atom) []Atom {
+	if a.Kind == AtomKindList {
+		return a.Value.(List)
+	}
+
+	h := a.Value.(Hash)
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	res := make([]Atom, 0, len(h)*2)
+	for _, k := range keys {
+		res = append(res, atomString(k), h[k])
+	}
+	return res
+}
+
+// flat returns single line representation of a
+func (p *printer) flat(a Atom) string {
+	flat := printer{truncate: p.truncate, visiting: p.visiting}
+	flat.write(a, 0)
+	return flat.sb.String()
+}
+
+// write writes a starting at column col
+func (p *printer) write(a Atom, col int) {
+	switch a.Kind {
+	case AtomKindString:
+		s := string(a.Value.(String))
+		if p.truncate && utf8.RuneCountInString(s) > pprintMaxString {
+			runes := []rune(s)
+			fmt.Fprintf(&p.sb, "%s...(%d more chars)", strconv.Quote(string(runes[:pprintMaxString])), len(runes)-pprintMaxString)
+			return
+		}
+		p.sb.WriteString(strconv.Quote(s))
+	case AtomKindFloat:
+		p.sb.WriteString(formatFloat(float64(a.Value.(Float))))
+	case AtomKindLambda:
+		la := a.Value.(Lambda)
+		form := []Atom{atomSymbol(fun.IF(la.isMacro, "macro", "fn")), atomList(la.params...), la.ast}
+		if len(la.clauses) > 0 {
+			form = append(form[:1], atomKeyword("clauses"))
+			for _, clause := range la.clauses {
+				form = append(form, atomList(atomList(clause.params...), clause.ast))
+			}
+		}
+		p.write(atomList(form...), col)
+	case AtomKindList, AtomKindHash:
+		p.writeColl(a, col)
+	default:
+		p.sb.WriteString(a.String())
+	}
+}
+
+// writeColl writes list or hash, breaking it into lines if it does not fit
+func (p *printer) writeColl(a Atom, col int) {
+	open, close, step := "(", ")", 1
+	if a.Kind == AtomKindHash {
+		open, close, step = "{", "}", 2
+	}
+
+	xs := items(a)
+	if len(xs) == 0 {
+		p.sb.WriteString(open + close)
+		return
+	}
+
+	line := ""
+	if p.width > 0 {
+		line = p.flat(a)
+	}
+
+	if id, ok := identity(a); ok {
+		if _, ok := p.visiting[id]; ok {
+			p.sb.WriteString("#cycle")
+			return
+		}
+		if p.visiting == nil {
+			p.visiting = map[uintptr]struct{}{}
+		}
+		p.visiting[id] = struct{}{}
+		defer delete(p.visiting, id)
+	}
+
+	more := 0
+	if p.truncate && len(xs) > pprintMaxItems*step {
+		more = (len(xs) - pprintMaxItems*step) / step
+		xs = xs[:pprintMaxItems*step]
+	}
+
+	if p.width > 0 && col+utf8.RuneCountInString(line) <= p.width {
+		p.sb.WriteString(line)
+		return
+	}
+
+	if p.width == 0 {
+		p.sb.WriteString(open)
+		for i, x := range xs {
+			if i > 0 {
+				p.sb.WriteString(" ")
+			}
+			p.write(x, col)
+		}
+		if more > 0 {
+			fmt.Fprintf(&p.sb, " ...(%d more)", more)
+		}
+		p.sb.WriteString(close)
+		return
+	}
+
+	// element or key and value per line, aligned after opening bracket
+	p.sb.WriteString(open)
+	for i := 0; i < len(xs); i += step {
+		if i > 0 {
+			p.sb.WriteString("\n" + strings.Repeat(" ", col+1))
+		}
+		if step == 1 {
+			p.write(xs[i], col+1)
+			continue
+		}
+
+		key := p.flat(xs[i])
+		p.sb.WriteString(key + " ")
+		p.write(xs[i+1], col+1+len(key)+1)
+	}
+	if more > 0 {
+		fmt.Fprintf(&p.sb, "\n%s...(%d more)", strings.Repeat(" ", col+1), more)
+	}
+	p.sb.WriteString(close)
+}
@@ -0,0 +1,104 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrint(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ast Atom
+		res string
+	}{
+		"print_true":       {atomBool(true), "true"},
+		"print_false":      {atomBool(false), "false"},
+		"print_float":      {atomFloat(3.14), "3.14"},
+		"print_int":        {atomInt(92), "92"},
+		"print_empty_list": {atomNil, "()"},
+		"print_list":       {atomList(atomInt(1), atomInt(2)), "(1 2)"},
+		"print_symbol":     {atomSymbol("abc"), "abc"},
+		"test_print_nice":  {atomString("\n"), "\n"},
+		"test_print_dict": {atomHash(map[string]Atom{
+			"a": atomInt(1),
+			"b": atomString("2"),
+		}), `{"a" 1 "b" "2"}`},
+		"print_func": {atomFunc(func(x ...Atom) Atom { return x[0] }), "#fn"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.ast.String(), tc.res)
+		})
+	}
+}
+
+func TestPrintDebug(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ast Atom
+		res string
+	}{
+		"print_nil":                  {atomNil, "()"},
+		"print_string":               {atomString("abc"), `"abc"`},
+		"print_string_with_slash":    {atomString(`\`), `"\\"`},
+		"print_string_with_2slashes": {atomString(`\\`), `"\\\\"`},
+		"print_string_with_newline":  {atomString("\n"), `"\n"`},
+		"test_print_debug_dict": {atomHash(map[string]Atom{
+			"a": atomInt(1),
+			"b": atomString("2"),
+		}), `{"a" 1 "b" "2"}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.ast.GoString(), tc.res)
+		})
+	}
+}
+
+func TestPrStrRoundTrip(t *testing.T) {
+	for name, a := range map[string]Atom{
+		"int":     atomInt(-3),
+		"float":   atomFloat(2.0),
+		"string":  atomString("a \"b\"\n\\"),
+		"keyword": atomKeyword("k"),
+		"symbol":  atomSymbol("sym"),
+		"list":    atomList(atomInt(1), atomList(atomString("x"), atomFloat(0.5)), atomNil),
+		"hash": atomHash(map[string]Atom{
+			"b": atomList(atomInt(1)),
+			"a": atomHash(map[string]Atom{"c": atomString("d")}),
+		}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, a, readValue(prStr(a)))
+		})
+	}
+}
+
+func TestPprint(t *testing.T) {
+	a := atomHash(map[string]Atom{
+		"name": atomString("lish"),
+		"deps": atomList(atomString("readline"), atomString("fun"), atomString("testify")),
+		"nested": atomHash(map[string]Atom{
+			"a": atomList(atomInt(1), atomInt(2), atomInt(3)),
+			"b": atomString("some long string"),
+		}),
+	})
+	assert.Equal(t, `{"deps" ("readline" "fun" "testify") "name" "lish" "nested" {"a" (1 2 3) "b" "some long string"}}`, pprint(a, 100))
+	assert.Equal(t, `{"deps" ("readline"
+         "fun"
+         "testify")
+ "name" "lish"
+ "nested" {"a" (1 2 3)
+           "b" "some long string"}}`, pprint(a, 30))
+
+	long := make([]Atom, pprintMaxItems+5)
+	for i := range long {
+		long[i] = atomInt(1)
+	}
+	assert.Contains(t, pprint(atomList(long...), 1000), " 1 ...(5 more))")
+	assert.Equal(t, `"aaaaa"...(1 more chars)`, strings.Replace(pprint(atomString(strings.Repeat("a", pprintMaxString+1)), 80), strings.Repeat("a", pprintMaxString-5), "", 1))
+}
+
+func TestPrintCycle(t *testing.T) {
+	h := map[string]Atom{}
+	h["self"] = atomHash(h)
+	assert.Equal(t, `{"self" #cycle}`, prStr(atomHash(h)))
+}
@@ -0,0 +1,209 @@
+package interp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+var (
+	_reInt   = regexp.MustCompile(`^-?\d+$`)
+	_reFloat = regexp.MustCompile(`^-?\d+\.\d*$`)
+)
+
+func readAtom(token string) Atom {
+	if token == "true" {
+		return atomBool(true)
+	} else if token == "false" {
+		return atomBool(false)
+	}
+
+	if _reInt.MatchString(token) {
+		n, _ := strconv.ParseInt(token, 10, 64)
+		return atomInt(n)
+	}
+
+	if _reFloat.MatchString(token) {
+		n, _ := strconv.ParseFloat(token, 64)
+		return atomFloat(n)
+	}
+
+	if s, err := strconv.Unquote(token); err == nil {
+		return atomString(s)
+	}
+
+	if len(token) > 1 && token[0] == ':' {
+		return atomKeyword(token[1:])
+	}
+
+	return atomSymbol(token)
+}
+
+type Frame struct {
+	a             Atom
+	isReaderMacro bool
+	isHash        bool // items are keys and values of hash being read
+	line          int  // line frame starts at
+}
+
+// atom returns atom read into frame
+func (f Frame) atom() Atom {
+	if !f.isHash {
+		return f.a
+	}
+
+	items := f.a.Value.(List)
+	if len(items)%2 != 0 {
+		return lisherr("hash must have even number of items, but got %d in %s", len(items), f.a)
+	}
+
+	hashmap := make(map[string]Atom, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		switch k := items[i]; k.Kind {
+		case AtomKindString:
+			hashmap[string(k.Value.(String))] = items[i+1]
+		case AtomKindKeyword:
+			hashmap[string(k.Value.(Keyword))] = items[i+1]
+		default:
+			return lisherr("hash key must be string or keyword, not %s", k)
+		}
+	}
+	return atomHash(hashmap)
+}
+
+type stack struct {
+	data []Frame
+}
+
+func (s *stack) push(a Atom, isReaderMacro bool, line int) {
+	s.data = append(s.data, Frame{a, isReaderMacro, false, line})
+}
+
+func (s *stack) pop() (Frame, bool) {
+	if len(s.data) == 0 {
+		return Frame{}, false
+	}
+
+	res := s.data[len(s.data)-1]
+	s.data = s.data[:len(s.data)-1]
+	return res, true
+}
+
+// TODO: reader macro list, (add run-time)?
+func read_form(tokens []string, lines []int) Atom {
+	lists_stack := stack{}
+	append_item_to_last_stack_list := func(lists_stack *stack, item Atom) {
+		n := len(lists_stack.data)
+
+		if n == 0 {
+			lists_stack.push(atomList(item), false, 0)
+			return
+		}
+
+		last := lists_stack.data[n-1]
+		switch last.a.Kind {
+		case AtomKindList:
+			lists_stack.data[n-1].a = Atom{AtomKindList, append(last.a.Value.(List), item)}
+		default:
+			panic("unimplemented")
+		}
+	}
+	for i := 0; i < len(tokens); i++ {
+		line := lines[i]
+		switch token := tokens[i]; token {
+		case "(":
+			lists_stack.push(atomNil, false, line)
+		case ")":
+			if i == len(tokens)-1 {
+				continue
+			}
+			last_list, _ := lists_stack.pop()
+			rememberPosition(last_list)
+			append_item_to_last_stack_list(&lists_stack, last_list.a)
+		case "{":
+			if len(lists_stack.data) == 0 {
+				// top level list hash is put into
+				lists_stack.push(atomNil, false, line)
+			}
+			lists_stack.data = append(lists_stack.data, Frame{atomNil, false, true, line})
+		case "}":
+			last_hash, ok := lists_stack.pop()
+			if !ok || !last_hash.isHash {
+				return lisherr("unexpected } in %s", strings.Join(tokens, " "))
+			}
+			append_item_to_last_stack_list(&lists_stack, last_hash.atom())
+		case "'":
+			lists_stack.push(atomList(atomSymbol("quote")), true, line)
+		case "`":
+			lists_stack.push(atomList(atomSymbol("quasiquote")), true, line)
+		case ",":
+			lists_stack.push(atomList(atomSymbol("unquote")), true, line)
+		case ",@":
+			lists_stack.push(atomList(atomSymbol("splice-unquote")), true, line)
+		default:
+			item := readAtom(token)
+			append_item_to_last_stack_list(&lists_stack, item)
+		}
+		for len(lists_stack.data) > 1 && lists_stack.data[len(lists_stack.data)-1].isReaderMacro && len(lists_stack.data[len(lists_stack.data)-1].a.Value.(List)[1:]) == 1 {
+			last_list, _ := lists_stack.pop()
+			append_item_to_last_stack_list(&lists_stack, last_list.a)
+		}
+	}
+	for len(lists_stack.data) > 1 {
+		last_list, _ := lists_stack.pop()
+		rememberPosition(last_list)
+		append_item_to_last_stack_list(&lists_stack, last_list.atom())
+	}
+
+	a, ok := lists_stack.pop()
+	rememberPosition(a)
+	return fun.IF(ok, a.atom(), atomNil)
+}
+
+var RE = regexp.MustCompile(`\s*(,@|[{}()'` + "`" + `,^@]|"(?:\\.|[^\\"])*"|;.*|[^\s{}()'"` + "`" + `,;]*)\s*`)
+
+func read(cmd string) Atom {
+	return read_form(tokenize(cmd))
+}
+
+// readValue reads single form, unlike read it does not turn lone atom into
+// call of it
+func readValue(cmd string) Atom {
+	tokens, lines := tokenize(cmd)
+	res := read_form(tokens, lines)
+	if len(tokens) > 0 && tokens[0] != "(" && res.Kind == AtomKindList && len(res.Value.(List)) == 1 {
+		return res.Value.(List)[0]
+	}
+	return res
+}
+
+// readLine reads line typed in repl or given to -c. Lone $NAME reads
+// variable, as calling its value makes no sense.
+func readLine(line string) Atom {
+	tokens, lines := tokenize(line)
+	res := read_form(tokens, lines)
+	if len(tokens) == 1 && len(tokens[0]) > 1 && tokens[0][0] == '$' && res.Kind == AtomKindList && len(res.Value.(List)) == 1 {
+		return res.Value.(List)[0]
+	}
+	return res
+}
+
+// tokenize splits cmd into tokens and lines they are at
+func tokenize(cmd string) ([]string, []int) {
+	tokens, lines := []string{}, []int{}
+	line, offset := 1, 0
+	for _, submatch := range RE.FindAllStringSubmatchIndex(cmd, -1) {
+		token := cmd[submatch[2]:submatch[3]]
+		line += strings.Count(cmd[offset:submatch[2]], "\n")
+		offset = submatch[2]
+		// skip comments
+		if token == "" || token[0] == ';' {
+			continue
+		}
+		tokens = append(tokens, token)
+		lines = append(lines, line)
+	}
+	return tokens, lines
+}
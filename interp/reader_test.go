@@ -1,4 +1,4 @@
-package main
+package interp
 
 import (
 	"testing"
@@ -38,11 +38,13 @@ func TestParse(t *testing.T) {
 		"star_expr":               {"(* 1 2)", atomList(atomSymbol("*"), atomInt(1), atomInt(2))},
 		"pow_expr":                {"(** 1 2)", atomList(atomSymbol("**"), atomInt(1), atomInt(2))},
 		"star_negnum_expr":        {"(* -1 2)", atomList(atomSymbol("*"), atomInt(-1), atomInt(2))},
+		"keyword":                 {":stdout", atomList(atomKeyword("stdout"))},
 		"string_spaces":           {`   "abc"   `, atomList(atomString("abc"))},
 		"quote_list":              {"'(a b c)", atomList(atomSymbol("quote"), atomList(atomSymbol("a"), atomSymbol("b"), atomSymbol("c")))},
 		"quote_symbol":            {"'a", atomList(atomSymbol("quote"), atomSymbol("a"))},
 		"unquote_symbol":          {"`(,a b)", atomList(atomSymbol("quasiquote"), atomList(atomList(atomSymbol("unquote"), atomSymbol("a")), atomSymbol("b")))},
 		"comment":                 {"123 ; such number", atomList(atomInt(123))},
+		"hash_bang_symbol":        {"(a #!b)", atomList(atomSymbol("a"), atomSymbol("#!b"))},
 		"string_arg_l":            {`(load-file "compose.lish"`, atomList(atomSymbol("load-file"), atomString("compose.lish"))},
 		"string_arg_r":            {`load-file "compose.lish")`, atomList(atomSymbol("load-file"), atomString("compose.lish"))},
 		"right_outer_list_simple": {"(+ 1 2", atomList(atomSymbol("+"), atomInt(1), atomInt(2))},
@@ -52,6 +54,9 @@ func TestParse(t *testing.T) {
 		"left_outer_twice":        {"+-curried 1) 3)", atomList(atomList(atomSymbol("+-curried"), atomInt(1)), atomInt(3))},
 		"outer_left_outer":        {"+-curried 1) 3", atomList(atomList(atomSymbol("+-curried"), atomInt(1)), atomInt(3))},
 		"outer_right_outer":       {"+ 1 2 (+ 3 4", atomList(atomSymbol("+"), atomInt(1), atomInt(2), atomList(atomSymbol("+"), atomInt(3), atomInt(4)))},
+		"dict_keywords": {`{:a 1}`, atomList(atomHash(map[string]Atom{
+			"a": atomInt(1),
+		}))},
 		"dict": {`{"a" 1 "b" "2"`, atomList(atomHash(map[string]Atom{
 			"a": atomInt(1),
 			"b": atomString("2"),
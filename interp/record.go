@@ -0,0 +1,416 @@
+package interp
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rprtr258/fun"
+)
+
+// recordType is kind of records defined by defrecord
+type recordType struct {
+	name   string
+	fields []Symbol
+
+	mu sync.RWMutex
+	// implementations of Value protocol methods str, = and <
+	methods map[Symbol]method
+}
+
+// method is implementation of Value protocol method for record kind
+type method struct {
+	fn Atom
+}
+
+// call calls method with args. String and Cmp get no environment of their
+// caller, so method is run in scope it was defined in, detached from
+// evaluation which defined it, as that one is likely over by now.
+func (m method) call(args ...Atom) Atom {
+	env := newEnv(fun.Invalid[*Env]())
+	env.scope = m.fn.Value.(Lambda).env.scope.detached()
+	return call(m.fn, args, env)
+}
+
+func (t *recordType) method(name Symbol) (method, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	m, ok := t.methods[name]
+	return m, ok
+}
+
+// Record is value of kind defined by defrecord, fields are in order of
+// definition
+type Record struct {
+	typ    *recordType
+	fields []Atom
+	// allocated once record is made, tells apart records = of whose kind
+	// does not hold for though fields are equal
+	id *byte
+}
+
+// String returns result of str method of record kind if it is implemented,
+// #name{:field value ...} otherwise
+func (r Record) String() string {
+	m, ok := r.typ.method("str")
+	if !ok {
+		return r.GoString()
+	}
+
+	// str method printing record itself gets #name{...} instead of recursing
+	key := recordKey{r.typ, reflect.ValueOf(r.fields).Pointer()}
+	stringing.mu.Lock()
+	if _, ok := stringing.records[key]; ok {
+		stringing.mu.Unlock()
+		return r.GoString()
+	}
+	stringing.records[key] = struct{}{}
+	stringing.mu.Unlock()
+	defer func() {
+		stringing.mu.Lock()
+		delete(stringing.records, key)
+		stringing.mu.Unlock()
+	}()
+
+	if res := m.call(Atom{AtomKindRecord, r}); res.Kind == AtomKindString {
+		return string(res.Value.(String))
+	}
+	return r.GoString()
+}
+
+// recordKey identifies record by its kind and fields
+type recordKey struct {
+	typ    *recordType
+	fields uintptr
+}
+
+// stringing are records str method is being called for
+var stringing = struct {
+	mu      sync.Mutex
+	records map[recordKey]struct{}
+}{records: map[recordKey]struct{}{}}
+
+func (r Record) GoString() string {
+	var sb strings.Builder
+	sb.WriteString("#" + r.typ.name + "{")
+	for i, field := range r.typ.fields {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		fmt.Fprintf(&sb, ":%s %s", field, prStr(r.fields[i]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// holds reports whether method called with args returns truthy value
+func (m method) holds(args ...Atom) bool {
+	res := m.call(args...)
+	return res.Kind != AtomKindError && truthy(res)
+}
+
+// Cmp orders records by name of their kind, records of the same kind are
+// compared with = and < methods of the kind, or field by field if they are
+// not implemented. Methods must agree with each other, as fields do not.
+// Records = tells apart are never equal, even if their fields are.
+func (r Record) Cmp(other Value) int {
+	o := other.(Record)
+	if r.typ != o.typ {
+		if c := cmp.Compare(r.typ.name, o.typ.name); c != 0 {
+			return c
+		}
+		return cmpIdentity(r.typ, o.typ)
+	}
+
+	a, b := Atom{AtomKindRecord, r}, Atom{AtomKindRecord, o}
+	eq, hasEq := r.typ.method("=")
+	if hasEq && eq.holds(a, b) {
+		return 0
+	}
+	if lt, ok := r.typ.method("<"); ok {
+		switch {
+		case lt.holds(a, b):
+			return -1
+		case lt.holds(b, a):
+			return 1
+		case !hasEq:
+			return 0
+		}
+	}
+
+	if c := List(r.fields).Cmp(List(o.fields)); c != 0 || !hasEq {
+		return c
+	}
+	// fields are equal, but = tells records apart
+	return cmpIdentity(r.id, o.id)
+}
+
+// protocolType is kind of protocols defined by defprotocol, whose methods are
+// list of names of generic functions
+var protocolType = &recordType{name: "protocol", fields: []Symbol{"name", "methods"}}
+
+// valueProtocol is protocol of builtins records might override, like Value
+// interface of Go values
+var valueProtocol = Atom{AtomKindRecord, Record{protocolType, []Atom{
+	atomString("Value"),
+	atomList(atomSymbol("str"), atomSymbol("="), atomSymbol("<")),
+}, new(byte)}}
+
+// isValueProtocol reports whether protocol is Value one itself, not other
+// protocol named the same
+func isValueProtocol(protocol Record) bool {
+	return protocol.typ == protocolType && cmpIdentity(protocol.fields, valueProtocol.Value.(Record).fields) == 0
+}
+
+// genericFunc is protocol method dispatching on kind of its first argument
+type genericFunc struct {
+	mu sync.RWMutex
+	// implementations by kinds
+	impls map[kindKey]Atom
+}
+
+// kindKey identifies kind of values, records of the same name defined again
+// or named after builtin kind are of kinds of their own
+type kindKey struct {
+	kind   AtomKind
+	record *recordType // nil for builtin kinds
+}
+
+func kindOf(a Atom) kindKey {
+	if a.Kind == AtomKindRecord {
+		return kindKey{a.Kind, a.Value.(Record).typ}
+	}
+	return kindKey{a.Kind, nil}
+}
+
+// kindName returns name of kind of a, records are of kinds named by defrecord
+func kindName(a Atom) string {
+	if a.Kind == AtomKindRecord {
+		return a.Value.(Record).typ.name
+	}
+	return string(a.Kind)
+}
+
+// builtinKinds are names of kinds extend accepts besides records
+var builtinKinds = []AtomKind{
+	AtomKindBool, AtomKindInt, AtomKindFloat, AtomKindString, AtomKindKeyword, AtomKindError, AtomKindHash,
+	AtomKindStream, AtomKindFuture, AtomKindSymbol, AtomKindFunc, AtomKindLambda, AtomKindList,
+}
+
+// symbols returns names of list of symbols, error if some item is not a symbol
+func symbols(form string, a Atom) ([]Symbol, Atom, bool) {
+	l, ok := a.Value.(List)
+	if !ok {
+		return nil, lisherr("%s: %s is not a list of names", form, a), false
+	}
+
+	res := make([]Symbol, len(l))
+	for i, x := range l {
+		if x.Kind != AtomKindSymbol {
+			return nil, lisherr("%s: %s is not a symbol", form, x), false
+		}
+		res[i] = x.Value.(Symbol)
+	}
+	return res, Atom{}, true
+}
+
+// evalDefrecord defines constructor of record kind name taking values of
+// fields, predicate name? and accessors name-field
+func evalDefrecord(l List, env Env) Atom {
+	if l[1].Kind != AtomKindSymbol {
+		return lisherr("defrecord: %s is not a symbol", l[1])
+	}
+	fields, err, ok := symbols("defrecord", l[2])
+	if !ok {
+		return err
+	}
+
+	name := l[1].Value.(Symbol)
+	typ := &recordType{name: string(name), fields: fields}
+	constructor := documented(
+		l[2].String(),
+		fmt.Sprintf("Returns %s record with fields set to values.", name),
+		atomFunc(func(args ...Atom) Atom {
+			if len(args) != len(fields) {
+				return arityError(&Meta{name: string(name)}, arity{len(fields), len(fields)}, len(args))
+			}
+			return Atom{AtomKindRecord, Record{typ, slices.Clone(args), new(byte)}}
+		}),
+	)
+	f := constructor.Value.(Func)
+	f.record = typ
+	env.set(name, named(Atom{AtomKindFunc, f}, name))
+
+	predicate := Symbol(name + "?")
+	env.set(predicate, named(documented("(x)", fmt.Sprintf("Returns whether x is %s record.", name), atomFunc(func(args ...Atom) Atom {
+		return atomBool(args[0].Kind == AtomKindRecord && args[0].Value.(Record).typ == typ)
+	}, signature(arg()))), predicate))
+
+	for i, field := range fields {
+		accessor := Symbol(fmt.Sprintf("%s-%s", name, field))
+		env.set(accessor, named(documented("(record)", fmt.Sprintf("Returns %s of %s record.", field, name), atomFunc(func(args ...Atom) Atom {
+			if args[0].Kind != AtomKindRecord || args[0].Value.(Record).typ != typ {
+				return lisherr("%s: %s is not %s record", accessor, args[0], name)
+			}
+			return args[0].Value.(Record).fields[i]
+		}, signature(arg()))), accessor))
+	}
+	return atomNil
+}
+
+// evalDefprotocol defines protocol name and its methods, generic functions
+// calling implementation for kind of their first argument
+func evalDefprotocol(l List, env Env) Atom {
+	if l[1].Kind != AtomKindSymbol {
+		return lisherr("defprotocol: %s is not a symbol", l[1])
+	}
+
+	name := l[1].Value.(Symbol)
+	methods := make([]Atom, len(l[2:]))
+	protocol := &Record{protocolType, []Atom{atomString(name), atomNil}, new(byte)}
+	for i, spec := range l[2:] {
+		s, ok := spec.Value.(List)
+		if !ok || len(s) < 2 || len(s) > 3 || s[0].Kind != AtomKindSymbol || s[1].Kind != AtomKindList || len(s[1].Value.(List)) == 0 {
+			return lisherr("defprotocol: method must be (name (x & params) [doc]), not %s", spec)
+		}
+		a, err := paramsArity(s[1].Value.(List))
+		if err != nil {
+			return lisherr("defprotocol: invalid params: %s", err)
+		}
+
+		methodName := s[0].Value.(Symbol)
+		doc := fmt.Sprintf("Method of %s protocol.", name)
+		if len(s) == 3 && s[2].Kind == AtomKindString {
+			doc = string(s[2].Value.(String))
+		}
+		generic := &genericFunc{impls: map[kindKey]Atom{}}
+		fn := documented(s[1].String(), doc, atomFuncEnv(func(env Env, args ...Atom) Atom {
+			if !a.accepts(len(args)) {
+				return arityError(&Meta{name: string(methodName)}, a, len(args))
+			}
+
+			generic.mu.RLock()
+			impl, ok := generic.impls[kindOf(args[0])]
+			generic.mu.RUnlock()
+			if !ok {
+				return lisherr("%s is not implemented for %s", methodName, kindName(args[0]))
+			}
+			return call(impl, args, env)
+		}))
+		f := fn.Value.(Func)
+		f.generic = generic
+		env.set(methodName, named(Atom{AtomKindFunc, f}, methodName))
+		methods[i] = s[0]
+	}
+	protocol.fields[1] = atomList(methods...)
+	env.set(name, Atom{AtomKindRecord, *protocol})
+	return atomNil
+}
+
+// evalExtend implements methods of protocols for kind, which is name of
+// record or builtin kind:
+//
+//	(extend kind protocol (method params body) ... protocol ...)
+func evalExtend(l List, env Env) Atom {
+	if l[1].Kind != AtomKindSymbol {
+		return lisherr("extend: %s is not a kind", l[1])
+	}
+
+	kind := l[1].Value.(Symbol)
+	var typ *recordType
+	if constructor, ok := lookup(env, kind); ok && constructor.Kind == AtomKindFunc && constructor.Value.(Func).record != nil {
+		typ = constructor.Value.(Func).record
+	} else if !slices.Contains(builtinKinds, AtomKind(kind)) {
+		return lisherr("extend: unknown kind %s", kind)
+	}
+
+	var protocol Record
+	for _, form := range l[2:] {
+		if form.Kind == AtomKindSymbol {
+			p := eval(form, env)
+			if p.Kind != AtomKindRecord || p.Value.(Record).typ != protocolType {
+				return lisherr("extend: %s is not a protocol", form)
+			}
+			protocol = p.Value.(Record)
+			continue
+		}
+
+		impl, ok := form.Value.(List)
+		if !ok || len(impl) < 3 || impl[0].Kind != AtomKindSymbol {
+			return lisherr("extend: method must be (name params & body), not %s", form)
+		}
+		if protocol.typ == nil {
+			return lisherr("extend: method %s is not preceded by protocol", impl[0])
+		}
+		methodName := impl[0].Value.(Symbol)
+		if !slices.Contains(protocol.fields[1].Value.(List), impl[0]) {
+			return lisherr("extend: %s is not method of %s protocol", methodName, protocol.fields[0].Value.(String))
+		}
+
+		fn := makeLambda(List{atomSymbol("fn"), impl[1], implicitProgn(impl[2:])}, env)
+		if fn.Kind == AtomKindError {
+			return fn
+		}
+		fn = named(fn, Symbol(fmt.Sprintf("%s.%s", kind, methodName)))
+
+		if isValueProtocol(protocol) {
+			// builtins are overridden by records themselves
+			if typ == nil {
+				return lisherr("extend: Value protocol is implemented by records only, not %s", kind)
+			}
+			typ.mu.Lock()
+			if typ.methods == nil {
+				typ.methods = map[Symbol]method{}
+			}
+			typ.methods[methodName] = method{fn}
+			typ.mu.Unlock()
+			continue
+		}
+
+		generic, ok := lookup(env, methodName)
+		if !ok || generic.Kind != AtomKindFunc || generic.Value.(Func).generic == nil {
+			return lisherr("extend: %s is not a protocol method", methodName)
+		}
+		g := generic.Value.(Func).generic
+		g.mu.Lock()
+		key := kindKey{AtomKind(kind), nil}
+		if typ != nil {
+			key = kindKey{AtomKindRecord, typ}
+		}
+		g.impls[key] = fn
+		g.mu.Unlock()
+	}
+	return atomNil
+}
+
+// satisfies reports whether all methods of protocol are implemented for kind
+// of x
+func satisfies(env Env, protocol Record, x Atom) bool {
+	for _, m := range protocol.fields[1].Value.(List) {
+		if isValueProtocol(protocol) {
+			if x.Kind != AtomKindRecord {
+				return false
+			}
+			if _, ok := x.Value.(Record).typ.method(m.Value.(Symbol)); !ok {
+				return false
+			}
+			continue
+		}
+
+		generic, ok := lookup(env, m.Value.(Symbol))
+		if !ok || generic.Kind != AtomKindFunc || generic.Value.(Func).generic == nil {
+			return false
+		}
+		g := generic.Value.(Func).generic
+		g.mu.RLock()
+		_, ok = g.impls[kindOf(x)]
+		g.mu.RUnlock()
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package interp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecords(t *testing.T) {
+	const host = `(defrecord Host (name port))`
+	const version = `(defrecord Version (major minor))
+(extend Version Value
+  (str (v) (str (Version-major v) "." (Version-minor v)))
+  (< (a b) (if (= (Version-major a) (Version-major b))
+             (< (Version-minor a) (Version-minor b))
+             (< (Version-major a) (Version-major b)))))`
+	const show = `(defprotocol Show (show (x) "Returns x for humans."))
+(extend Host Show (show (h) (str (Host-name h) ":" (Host-port h))))
+(extend int64 Show (show (n) (str "#" n)))`
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"accessor":       {`(Host-port (Host "db" 5432))`, atomInt(5432)},
+		"predicate":      {`(list (Host? (Host "db" 1)) (Host? {:name "db"}) (Host? 1))`, atomList(atomBool(true), atomBool(false), atomBool(false))},
+		"printed":        {`(str (Host "db" 22))`, atomString(`#Host{:name "db" :port 22}`)},
+		"kind":           {`(list (kind (Host "db" 22)) (kind 1) (kind "s"))`, atomList(atomString("Host"), atomString("int64"), atomString("string"))},
+		"equal":          {`(list (= (Host "db" 22) (Host "db" 22)) (= (Host "db" 22) (Host "db" 23)))`, atomList(atomBool(true), atomBool(false))},
+		"fields_ordered": {`(< (Host "a" 9) (Host "b" 1))`, atomBool(true)},
+		"constructor":    {`(Host "db")`, lisherr("Host requires 2 argument(s), but got 1")},
+		"accessor_kind":  {`(Host-name "db")`, lisherr(`Host-name: db is not Host record`)},
+		"invalid_fields": {`(defrecord Host (name 1))`, lisherr("defrecord: 1 is not a symbol")},
+		"json":           {`(json/stringify (Host "db" 22))`, atomString(`{"name":"db","port":22}`)},
+		"value_str":      {version + `(str (Version 1 2))`, atomString("1.2")},
+		"value_lt":       {version + `(list (< (Version 1 10) (Version 2 0)) (< (Version 1 10) (Version 1 2)) (> (Version 1 10) (Version 1 2)))`, atomList(atomBool(true), atomBool(false), atomBool(true))},
+		"value_eq_lt":    {version + `(= (Version 1 2) (Version 1 2))`, atomBool(true)},
+		"value_eq":       {`(defrecord Id (n tag)) (extend Id Value (= (a b) (= (Id-n a) (Id-n b)))) (list (= (Id 1 "x") (Id 1 "y")) (= (Id 1 "x") (Id 2 "x")))`, atomList(atomBool(true), atomBool(false))},
+		"value_eq_empty": {`(defrecord E ()) (extend E Value (= (a b) false)) (let (x (E) y (E)) (list (= x y) (= (< x y) (> y x)) (= (< x y) (< y x))))`, atomList(atomBool(false), atomBool(true), atomBool(false))},
+		"value_eq_only":  {`(defrecord Id (n)) (extend Id Value (= (a b) false)) (let (x (Id 1) y (Id 1)) (list (= x y) (= (< x y) (> x y))))`, atomList(atomBool(false), atomBool(false))},
+		"value_str_self": {`(extend Host Value (str (h) (str "<" h ">"))) (str (Host "db" 22))`, atomString(`<#Host{:name "db" :port 22}>`)},
+		"value_named":    {`(defprotocol Value (size (x))) (extend int64 Value (size (n) n)) (size 3)`, atomInt(3)},
+		"value_builtin":  {`(extend int64 Value (str (n) "n"))`, lisherr("extend: Value protocol is implemented by records only, not int64")},
+		"protocol":       {show + `(list (show (Host "db" 22)) (show 1))`, atomList(atomString("db:22"), atomString("#1"))},
+		"protocol_none":  {show + `(show "s")`, lisherr("show is not implemented for string")},
+		"protocol_arity": {show + `(show 1 2)`, lisherr("show requires 1 argument(s), but got 2")},
+		"satisfies":      {show + `(list (satisfies? Show (Host "db" 22)) (satisfies? Show "s") (satisfies? Value (Host "db" 22)))`, atomList(atomBool(true), atomBool(false), atomBool(false))},
+		"shadow_builtin": {show + `(defrecord string (s)) (extend string Show (show (s) "record")) (list (show "s") (show (string "s")))`, atomList(lisherr("show is not implemented for string"), atomString("record"))},
+		"redefined":      {show + `(defrecord Host (name port)) (show (Host "db" 22))`, lisherr("show is not implemented for Host")},
+		"generic_args":   {`(defprotocol Scale (scale (x k))) (extend int64 Scale (scale (n k) (* n k))) (scale 3 4)`, atomInt(12)},
+		"generic_body":   {show + `(extend string Show (show (s) (set t (str s "!")) t)) (show "hi")`, atomString("hi!")},
+		"unknown_kind":   {`(extend Nope Value)`, lisherr("extend: unknown kind Nope")},
+		"not_protocol":   {`(extend int64 Host)`, lisherr("extend: Host is not a protocol")},
+		"not_method":     {show + `(extend string Show (shout (s) s))`, lisherr("extend: shout is not method of Show protocol")},
+		"no_protocol":    {`(extend string (show (s) s))`, lisherr("extend: method show is not preceded by protocol")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := "(progn " + host + tc.input + ")"
+			assert.Equal(t, tc.res, eval(readValue(input), newEnvRepl()))
+			assert.Equal(t, tc.res, evalCompiled(readValue(input), newEnvRepl()))
+		})
+	}
+}
+
+func TestRecordMethodsOutliveEval(t *testing.T) {
+	in := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := in.Eval(ctx, `(defrecord P (x)) (extend P Value (str (p) "p") (= (a b) true))`)
+	assert.NoError(t, err)
+	// as repl does once line is evaluated
+	cancel()
+
+	// methods keep working once evaluation which defined them is over
+	res, err := in.Eval(context.Background(), `(list (str (P 1)) (= (P 1) (P 2)))`)
+	assert.NoError(t, err)
+	assert.Equal(t, atomList(atomString("p"), atomBool(true)), res)
+}
@@ -0,0 +1,261 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type streamKind int
+
+const (
+	// stdin is inherited, stdout and stderr are captured
+	streamDefault streamKind = iota
+	streamCapture
+	streamInherit
+	streamNull
+	streamFile
+	// text is fed to stdin
+	streamString
+	// stderr goes to stdout (2>&1) or stdout goes to stderr (1>&2)
+	streamMerge
+)
+
+type stream struct {
+	kind   streamKind
+	path   string // file path for streamFile
+	append bool   // append to file instead of truncating it
+	text   string // text for streamString
+}
+
+// ioSpec is a redirections of command standard streams set by with-io
+type ioSpec struct {
+	stdin, stdout, stderr stream
+}
+
+// merge returns spec with streams set in other replacing ones in spec
+func (spec ioSpec) merge(other ioSpec) ioSpec {
+	for _, s := range []struct{ dst, src *stream }{
+		{&spec.stdin, &other.stdin},
+		{&spec.stdout, &other.stdout},
+		{&spec.stderr, &other.stderr},
+	} {
+		if s.src.kind != streamDefault {
+			*s.dst = *s.src
+		}
+	}
+	return spec
+}
+
+// parseStream parses redirection of stream named name from with-io options
+func parseStream(name string, a Atom) (stream, error) {
+	switch a.Kind {
+	case AtomKindString:
+		if name != "stdin" {
+			return stream{}, fmt.Errorf("%s can't be redirected from string, use (file path) to write into file", name)
+		}
+		return stream{kind: streamString, text: string(a.Value.(String))}, nil
+	case AtomKindKeyword:
+		switch k := a.Value.(Keyword); {
+		case k == "inherit":
+			return stream{kind: streamInherit}, nil
+		case k == "null":
+			return stream{kind: streamNull}, nil
+		case k == "capture" && name != "stdin":
+			return stream{kind: streamCapture}, nil
+		case k == "stdout" && name == "stderr", k == "stderr" && name == "stdout":
+			return stream{kind: streamMerge}, nil
+		}
+	case AtomKindHash:
+		h := a.Value.(Hash)
+		if path, ok := h["file"]; ok && path.Kind == AtomKindString {
+			appendMode := false
+			if v, ok := h["append"]; ok && v.Kind == AtomKindBool {
+				appendMode = bool(v.Value.(Bool))
+			}
+			return stream{kind: streamFile, path: string(path.Value.(String)), append: appendMode}, nil
+		}
+	}
+	return stream{}, fmt.Errorf("%s can't be redirected to %s", name, a)
+}
+
+// parseIOSpec parses with-io options hash
+func parseIOSpec(opts Hash) (ioSpec, error) {
+	var spec ioSpec
+	for k, v := range opts {
+		var dst *stream
+		switch k {
+		case "stdin":
+			dst = &spec.stdin
+		case "stdout":
+			dst = &spec.stdout
+		case "stderr":
+			dst = &spec.stderr
+		default:
+			return ioSpec{}, fmt.Errorf("unknown with-io option %s", k)
+		}
+
+		s, err := parseStream(k, v)
+		if err != nil {
+			return ioSpec{}, err
+		}
+		*dst = s
+	}
+
+	if spec.stdout.kind == streamMerge && spec.stderr.kind == streamMerge {
+		return ioSpec{}, errors.New("stdout and stderr can't be redirected into each other")
+	}
+	return spec, nil
+}
+
+// output opens output stream, returned buffer is not nil if output is captured.
+// Relative file path is resolved against current directory of scope.
+func (s stream) output(scope *shellScope, files *[]*os.File, inherit *os.File) (io.Writer, *bytes.Buffer, error) {
+	switch s.kind {
+	case streamDefault, streamCapture:
+		var buf bytes.Buffer
+		return &buf, &buf, nil
+	case streamInherit:
+		return inherit, nil, nil
+	case streamNull:
+		return nil, nil, nil
+	case streamFile:
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if s.append {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		path, err := scope.filePath(s.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		*files = append(*files, f)
+		return f, nil, nil
+	default:
+		panic("unreachable")
+	}
+}
+
+// runCommand runs external command and returns hash with its exit code and
+// captured outputs, output which is not captured is nil. Command killed on
+// cancellation results in cancelled error.
+func runCommand(env Env, program string, args []Atom) Atom {
+	args, timeout, err := commandOptions(args)
+	if err != nil {
+		return lisherr("%s", err)
+	}
+	if timeout > 0 {
+		scope, cancel := env.scope.withTimeout(timeout)
+		defer cancel()
+		env.scope = scope
+	}
+
+	cmdArgs, err := commandArgs(env, args)
+	if err != nil {
+		return lisherr("%s", err)
+	}
+
+	child, err := newCommand(env, program, cmdArgs)
+	if err != nil {
+		return lisherr("%s", err)
+	}
+
+	spec := env.scope.io()
+	files := []*os.File{}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	switch spec.stdin.kind {
+	case streamDefault, streamInherit:
+		child.Stdin = os.Stdin
+	case streamNull:
+	case streamString:
+		child.Stdin = strings.NewReader(spec.stdin.text)
+	case streamFile:
+		path, err := env.scope.readPath(spec.stdin.path)
+		if err != nil {
+			return lisherr("%s", err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return lisherr("%s", err)
+		}
+		files = append(files, f)
+		child.Stdin = f
+	}
+
+	var stdout, stderr *bytes.Buffer
+	if spec.stdout.kind != streamMerge {
+		if child.Stdout, stdout, err = spec.stdout.output(env.scope, &files, os.Stdout); err != nil {
+			return lisherr("%s", err)
+		}
+	}
+	if spec.stderr.kind != streamMerge {
+		if child.Stderr, stderr, err = spec.stderr.output(env.scope, &files, os.Stderr); err != nil {
+			return lisherr("%s", err)
+		}
+	}
+	switch {
+	case spec.stdout.kind == streamMerge:
+		child.Stdout = child.Stderr
+	case spec.stderr.kind == streamMerge:
+		child.Stderr = child.Stdout
+	}
+
+	status := 0
+	if err := child.Run(); err != nil {
+		if cancelled, ok := env.scope.cancelled(); ok {
+			return cancelled
+		}
+
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return lisherr("%s", err)
+		}
+		status = exitErr.ExitCode()
+	}
+
+	env.root().set("$?", atomInt(status))
+
+	captured := func(buf *bytes.Buffer) Atom {
+		if buf == nil {
+			return atomNil
+		}
+		return atomString(buf.String())
+	}
+	// TODO: stdout is iter (another kind of list) of lines
+	return atomHash(map[string]Atom{
+		"exit_code": atomInt(status),
+		"stdout":    captured(stdout),
+		"stderr":    captured(stderr),
+	})
+}
+
+func builtinFile(_ Env, args ...Atom) Atom {
+	if args[0].Kind != AtomKindString {
+		return lisherr("file path must be string, not %s", args[0])
+	}
+
+	appendMode := false
+	for _, flag := range args[1:] {
+		if flag != atomKeyword("append") {
+			return lisherr("unknown file flag %s", flag)
+		}
+		appendMode = true
+	}
+	return atomHash(map[string]Atom{
+		// path is checked and resolved once file is opened by command
+		"file":   args[0],
+		"append": atomBool(appendMode),
+	})
+}
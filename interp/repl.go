@@ -0,0 +1,125 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+// Prompt returns result of *PROMPT* function if it is set, current directory
+// otherwise
+func (in *Interp) Prompt() string {
+	env := in.env
+	if fn, ok := env.get("*PROMPT*"); ok {
+		res := call(fn, nil, env)
+		if res.Kind == AtomKindString {
+			return string(res.Value.(String))
+		}
+		return res.String()
+	}
+
+	dir, err := env.scope.getwd()
+	if err != nil {
+		return "=> "
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			dir = filepath.Join("~", rel)
+		}
+	}
+	return dir + "=> "
+}
+
+// Complete completes word before pos in line: file paths relative to current
+// directory and symbols defined in environment. Suffixes to append to word
+// are returned along with word length.
+func (in *Interp) Complete(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && !strings.ContainsRune(" \t()'`,\"", line[start-1]) {
+		start--
+	}
+	word := string(line[start:pos])
+	inString := start > 0 && line[start-1] == '"'
+
+	candidates := []string{}
+	if inString || strings.ContainsAny(word, "/~") || strings.HasPrefix(word, ".") {
+		candidates = completePath(in.env.scope, word)
+	} else {
+		for env := &in.env; ; env = env.Outer.Value {
+			for name := range env.bindings() {
+				if strings.HasPrefix(string(name), word) {
+					candidates = append(candidates, string(name)[len(word):])
+				}
+			}
+			if !env.Outer.Valid {
+				break
+			}
+		}
+	}
+
+	slices.Sort(candidates)
+	return fun.Map[[]rune](func(s string) []rune { return []rune(s) }, slices.Compact(candidates)...), len([]rune(word))
+}
+
+// completePath returns suffixes of files whose path starts with prefix
+func completePath(scope *shellScope, prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	absDir, err := scope.readPath(fun.IF(dir == "", ".", dir))
+	if err != nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return nil
+	}
+
+	res := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+
+		suffix := name[len(base):]
+		if entry.IsDir() {
+			suffix += "/"
+		}
+		res = append(res, suffix)
+	}
+	return res
+}
+
+// Display returns how result is shown to user: strings as is, lists of hashes
+// as tables, other values pretty printed, nothing for nil
+func Display(a Atom) (string, bool) {
+	switch {
+	case a.Kind == AtomKindList && len(a.Value.(List)) == 0:
+		return "", false
+	case a.Kind == AtomKindString:
+		return a.String(), true
+	case a.Kind == AtomKindList && isTable(a.Value.(List)):
+		if table, ok := formatTable(a.Value.(List), screenWidth()); ok {
+			return table, true
+		}
+		return pprint(a, screenWidth()), true
+	default:
+		return pprint(a, screenWidth()), true
+	}
+}
+
+// Status returns exit code of lish evaluated to a: 1 if a is error, exit code
+// if a is command result, 0 otherwise
+func Status(a Atom) int {
+	switch {
+	case a.Kind == AtomKindError:
+		return 1
+	case isCommandResult(a):
+		return int(a.Value.(Hash)["exit_code"].Value.(Int))
+	default:
+		return 0
+	}
+}
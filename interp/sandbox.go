@@ -0,0 +1,158 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// errNoFiles is returned for file access in sandbox
+var errNoFiles = errors.New("file access is disabled in sandbox")
+
+// filePath resolves path of file evaluated code is about to write or
+// remove, failing if sandbox forbids it
+func (s *shellScope) filePath(path string) (string, error) {
+	if s.files() != nil {
+		return "", errNoFiles
+	}
+	return s.resolvePath(path)
+}
+
+// readPath resolves path of file or directory evaluated code is about to
+// read, failing if sandbox forbids it. In sandbox symlinks are resolved too,
+// so that they do not lead out of readable directories.
+func (s *shellScope) readPath(path string) (string, error) {
+	resolved, err := s.resolvePath(path)
+	if err != nil || s.files() == nil {
+		return resolved, err
+	}
+
+	real, err := filepath.EvalSymlinks(resolved)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		// let caller report missing file, if it is readable at all
+		real = resolved
+	}
+	for _, dir := range s.sandbox.Read {
+		if isInside(real, dir) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("access to %s is disabled in sandbox", path)
+}
+
+// isInside returns true if path is dir or is inside of it
+func isInside(path, dir string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// files returns error if sandbox forbids file access
+func (s *shellScope) files() error {
+	if s != nil && s.sandbox.NoFiles {
+		return errNoFiles
+	}
+	return nil
+}
+
+// allowedCommand returns command running program if it is allowed in
+// sandbox, false if sandbox does not restrict commands at all
+func (s *shellScope) allowedCommand(program string, args []string) (*exec.Cmd, bool, error) {
+	if s == nil || !s.sandbox.NoExec {
+		return nil, false, nil
+	}
+	if !slices.Contains(s.sandbox.Exec, program) {
+		return nil, true, fmt.Errorf("running %s is disabled in sandbox", program)
+	}
+
+	// PATH and other variables set by evaluated code might substitute program
+	// or libraries it loads, so they are ignored
+	path, err := exec.LookPath(program)
+	if err != nil {
+		return nil, true, err
+	}
+	child := exec.CommandContext(s.context(), path, args...)
+	child.Args[0] = program
+	child.Env = os.Environ()
+	return child, true, nil
+}
+
+// environment returns error if sandbox forbids changing process environment
+func (s *shellScope) environment() error {
+	if s != nil && s.sandbox.NoExec {
+		return errors.New("changing environment is disabled in sandbox")
+	}
+	return nil
+}
+
+// memoryCheckInterval is number of steps between heap size checks, reading
+// it on each step is too slow
+const memoryCheckInterval = 1 << 10
+
+// limits tracks resources used by single evaluation
+type limits struct {
+	steps atomic.Int64
+	// heap size when evaluation started
+	heap uint64
+	// set once heap grows too much, so that evaluation fails from then on
+	// even if heap shrinks back
+	heapExceeded atomic.Bool
+}
+
+// withLimits returns scope counting resources evaluation uses, if sandbox
+// limits them
+func (s *shellScope) withLimits() *shellScope {
+	if s == nil || s.sandbox.MaxSteps == 0 && s.sandbox.MaxMemory == 0 {
+		return s
+	}
+
+	res := *s
+	res.limits = &limits{heap: heapSize()}
+	return &res
+}
+
+// step counts evaluation step, returning error once limit is exceeded
+func (s *shellScope) step() (Atom, bool) {
+	if s == nil || s.limits == nil {
+		return Atom{}, false
+	}
+
+	steps := s.limits.steps.Add(1)
+	if limit := s.sandbox.MaxSteps; limit != 0 && steps > int64(limit) {
+		return lisherr("step limit of %d exceeded", limit), true
+	}
+	if limit := s.sandbox.MaxMemory; limit != 0 {
+		if steps%memoryCheckInterval == 0 {
+			if heap := heapSize(); heap > s.limits.heap && heap-s.limits.heap > limit {
+				s.limits.heapExceeded.Store(true)
+			}
+		}
+		if s.limits.heapExceeded.Load() {
+			return lisherr("memory limit of %d bytes exceeded", limit), true
+		}
+	}
+	return Atom{}, false
+}
+
+// heapSize returns number of bytes occupied by heap objects, including not
+// yet collected ones
+func heapSize() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
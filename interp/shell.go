@@ -0,0 +1,556 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rprtr258/fun"
+)
+
+// shellScope is a settings of external commands execution. It is dynamically
+// scoped, so commands run by a function see scope of the caller. Scope is
+// never modified after creation, child scope is made by copying instead.
+// The only exceptions are current directory which is changed by cd and
+// table of required modules.
+type shellScope struct {
+	// environment variables overrides, invalid value means variable is unset
+	env map[string]fun.Option[string]
+	// current directory of interpreter, or one set by with-dir
+	dir *workDir
+	// modules required by interpreter
+	modules *moduleTable
+	// standard streams redirections set by with-io
+	redirect ioSpec
+	// cancelled by with-timeout or interrupt in repl, nil if never cancelled
+	ctx context.Context
+	// cause of ctx cancellation once its deadline is exceeded
+	timeout error
+	// restrictions of evaluated code, inherited by all child scopes
+	sandbox Sandbox
+	// resources used by evaluation, nil if sandbox does not limit them
+	limits *limits
+	// top level forms are compiled into bytecode
+	bytecode bool
+	// paths of modules whose evaluation led to this one, innermost last
+	loading []string
+	// where warnings about code being read are written, nil to drop them
+	warnings io.Writer
+}
+
+func (s *shellScope) withEnv(overrides map[string]fun.Option[string]) *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.env = maps.Clone(res.env)
+	if res.env == nil {
+		res.env = make(map[string]fun.Option[string], len(overrides))
+	}
+	maps.Copy(res.env, overrides)
+	return &res
+}
+
+func (s *shellScope) withIO(spec ioSpec) *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.redirect = res.redirect.merge(spec)
+	return &res
+}
+
+// withTimeout returns scope cancelled after timeout, cancel must be called
+// once evaluation in scope is done
+func (s *shellScope) withTimeout(timeout time.Duration) (*shellScope, context.CancelFunc) {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	parent := s.context()
+	cause := fmt.Errorf("timeout %s exceeded", timeout)
+	ctx, cancel := context.WithTimeoutCause(parent, timeout, cause)
+	// outer timeout is kept if it is exceeded earlier
+	if outer, ok := parent.Deadline(); !ok || !outer.Before(time.Now().Add(timeout)) {
+		res.timeout = cause
+	}
+	res.ctx = ctx
+	return &res, cancel
+}
+
+// withSpawn returns scope of goroutine started by go, which outlives
+// evaluation that started it. Goroutine is cancelled along with s by
+// interrupt and once timeout of s is exceeded, but not by plain cancel done
+// once evaluation or with-timeout body is over. stop must be called once
+// goroutine is done.
+func (s *shellScope) withSpawn() (*shellScope, func()) {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	parent := s.context()
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(parent))
+	stopDeadline := func() bool { return false }
+	if deadline, ok := parent.Deadline(); ok && res.timeout != nil {
+		timer := time.AfterFunc(time.Until(deadline), func() { cancel(res.timeout) })
+		stopDeadline = timer.Stop
+	}
+	stopAfter := context.AfterFunc(parent, func() {
+		if cause := context.Cause(parent); cause != context.Canceled {
+			cancel(cause)
+		}
+	})
+	res.ctx = ctx
+	return &res, func() {
+		stopAfter()
+		stopDeadline()
+		cancel(nil)
+	}
+}
+
+// withContext returns scope cancelled along with ctx
+func (s *shellScope) withContext(ctx context.Context) *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.ctx = ctx
+	return &res
+}
+
+// detached returns scope like s which is not cancelled along with it and
+// counts resources used on its own
+func (s *shellScope) detached() *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.ctx, res.limits = nil, nil
+	return res.withLimits()
+}
+
+// withModuleLoading returns scope of evaluation of module at path, required
+// from evaluation in s
+func (s *shellScope) withModuleLoading(path string) *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.loading = append(slices.Clone(res.loading), path)
+	return &res
+}
+
+// modulesLoading returns paths of modules being evaluated by this evaluation,
+// innermost last
+func (s *shellScope) modulesLoading() []string {
+	if s == nil {
+		return nil
+	}
+	return s.loading
+}
+
+func (s *shellScope) context() context.Context {
+	if s == nil || s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// cancelled returns error evaluation in scope stops with once scope is
+// cancelled, cancelled? tells it from other errors
+func (s *shellScope) cancelled() (Atom, bool) {
+	ctx := s.context()
+	if ctx.Err() == nil {
+		return Atom{}, false
+	}
+	return lisherr("%s%s", cancelledPrefix, context.Cause(ctx)), true
+}
+
+// cancelledPrefix starts message of error evaluation is cancelled with
+const cancelledPrefix = "cancelled: "
+
+func isCancelled(a Atom) bool {
+	return a.Kind == AtomKindError && strings.HasPrefix(string(a.Value.(Error)), cancelledPrefix)
+}
+
+// warn writes warnings about code being read
+func (s *shellScope) warn(warnings []string) {
+	if s == nil || s.warnings == nil {
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(s.warnings, "warning: %s\n", w)
+	}
+}
+
+func (s *shellScope) io() ioSpec {
+	if s == nil {
+		return ioSpec{}
+	}
+	return s.redirect
+}
+
+func (s *shellScope) getenv(key string) (string, bool) {
+	if s != nil {
+		if v, ok := s.env[key]; ok {
+			return v.Value, v.Valid
+		}
+		if v, ok := s.wd().environ()[key]; ok {
+			return v.Value, v.Valid
+		}
+	}
+	return os.LookupEnv(key)
+}
+
+// environ returns process environment with scope overrides applied
+func (s *shellScope) environ() []string {
+	if s == nil {
+		return os.Environ()
+	}
+	overrides := s.wd().environ()
+	maps.Copy(overrides, s.env)
+	if len(overrides) == 0 {
+		return os.Environ()
+	}
+
+	res := []string{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; !ok {
+			res = append(res, kv)
+		}
+	}
+	for k, v := range overrides {
+		if v.Valid {
+			res = append(res, k+"="+v.Value)
+		}
+	}
+	return res
+}
+
+// commandArgs converts evaluated arguments into command argv. Symbols are
+// passed by name, keywords become flags: :v is -v and :verbose is --verbose,
+// lists are spliced. If *GLOB* is true, strings are tilde and glob expanded,
+// pattern without matches is passed as is.
+func commandArgs(env Env, args []Atom) ([]string, error) {
+	expand := false
+	if v, ok := env.get("*GLOB*"); ok {
+		expand = v.Kind == AtomKindBool && bool(v.Value.(Bool))
+	}
+
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg.Kind {
+		case AtomKindString:
+			s := string(arg.Value.(String))
+			if !expand {
+				res = append(res, s)
+				continue
+			}
+
+			if !hasGlobMeta(s) {
+				s, err := expandTilde(s)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, s)
+				continue
+			}
+
+			dir, err := env.scope.getwd()
+			if err != nil {
+				return nil, err
+			}
+			matches, err := glob(dir, s)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				matches = []string{s}
+			}
+			res = append(res, matches...)
+		case AtomKindSymbol:
+			res = append(res, string(arg.Value.(Symbol)))
+		case AtomKindKeyword:
+			name := string(arg.Value.(Keyword))
+			res = append(res, fun.IF(len(name) == 1, "-", "--")+name)
+		case AtomKindInt, AtomKindFloat:
+			res = append(res, arg.String())
+		case AtomKindList:
+			spliced, err := commandArgs(env, arg.Value.(List))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, spliced...)
+		default:
+			return nil, fmt.Errorf("%s is not string argument", arg)
+		}
+	}
+	return res, nil
+}
+
+// commandOptions separates options hash from command arguments. The only
+// option is :timeout in milliseconds, after which command is killed.
+func commandOptions(args []Atom) ([]Atom, time.Duration, error) {
+	rest := make([]Atom, 0, len(args))
+	timeout := time.Duration(0)
+	for _, arg := range args {
+		if arg.Kind != AtomKindHash {
+			rest = append(rest, arg)
+			continue
+		}
+
+		for k, v := range arg.Value.(Hash) {
+			switch {
+			case k == "timeout" && v.Kind == AtomKindInt && v.Value.(Int) > 0:
+				timeout = time.Duration(v.Value.(Int)) * time.Millisecond
+			case k == "timeout":
+				return nil, 0, fmt.Errorf("command timeout must be positive int, not %s", v)
+			default:
+				return nil, 0, fmt.Errorf("unknown command option %s", k)
+			}
+		}
+	}
+	return rest, timeout, nil
+}
+
+// newCommand prepares external command to be run with settings from env
+func newCommand(env Env, program string, args []string) (*exec.Cmd, error) {
+	if child, sandboxed, err := env.scope.allowedCommand(program, args); err != nil {
+		return nil, err
+	} else if sandboxed {
+		child.WaitDelay = time.Second
+		child.Dir = env.scope.wd().current()
+		return child, nil
+	}
+
+	path, ok := commands.lookup(env.scope, program)
+	if !ok {
+		return nil, commandNotFound(env, program)
+	}
+
+	child := exec.CommandContext(env.scope.context(), path, args...)
+	child.Args[0] = program
+	// let children finish writing to captured outputs after being killed
+	child.WaitDelay = time.Second
+	child.Env = env.scope.environ()
+	child.Dir = env.scope.wd().current()
+	return child, nil
+}
+
+// workDir is a current directory of the shell along with its history. It is
+// shared by scopes of one interpreter, so directory changed by cd is seen by
+// the caller.
+type workDir struct {
+	mu    sync.Mutex
+	path  string // empty until changed, process working directory is used
+	old   string // previous directory, cd - returns to it
+	stack []string
+}
+
+func (wd *workDir) getwd() (string, error) {
+	if wd.path != "" {
+		return wd.path, nil
+	}
+	return os.Getwd()
+}
+
+// environ returns PWD and OLDPWD variables once directory is changed
+func (wd *workDir) environ() map[string]fun.Option[string] {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	res := map[string]fun.Option[string]{}
+	if wd.path != "" {
+		res["PWD"] = fun.Valid(wd.path)
+	}
+	if wd.old != "" {
+		res["OLDPWD"] = fun.Valid(wd.old)
+	}
+	return res
+}
+
+// current returns directory commands are run in, empty for process one
+func (wd *workDir) current() string {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.path
+}
+
+func (s *shellScope) wd() *workDir {
+	if s == nil || s.dir == nil {
+		return &workDir{}
+	}
+	return s.dir
+}
+
+func (s *shellScope) withDir(dir string) *shellScope {
+	res := shellScope{}
+	if s != nil {
+		res = *s
+	}
+	res.dir = &workDir{path: dir}
+	return &res
+}
+
+func (s *shellScope) getwd() (string, error) {
+	wd := s.wd()
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.getwd()
+}
+
+// chdir changes current directory, dir is resolved relative to current one
+func (s *shellScope) chdir(dir string) (string, error) {
+	dir, err := s.readPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(dir); err != nil {
+		return "", err
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+
+	wd := s.wd()
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	old, err := wd.getwd()
+	if err != nil {
+		return "", err
+	}
+
+	// process directory is left as is, as it is shared by all interpreters
+	wd.path, wd.old = dir, old
+	return dir, nil
+}
+
+// resolvePath expands ~ and makes path absolute using current directory
+func (s *shellScope) resolvePath(path string) (string, error) {
+	path, err := expandTilde(path)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	wd, err := s.getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, path), nil
+}
+
+// expandTilde replaces leading ~ or ~user with home directory
+func expandTilde(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+
+	name, rest, _ := strings.Cut(path[1:], "/")
+	var home string
+	if name == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, rest), nil
+}
+
+func builtinCd(env Env, args ...Atom) Atom {
+	dir := "~"
+	if len(args) == 1 {
+		dir = string(args[0].Value.(String))
+	}
+	if dir == "-" {
+		wd := env.scope.wd()
+		wd.mu.Lock()
+		dir = wd.old
+		wd.mu.Unlock()
+		if dir == "" {
+			return lisherr("cd: no previous directory")
+		}
+	}
+
+	newDir, err := env.scope.chdir(dir)
+	if err != nil {
+		return lisherr("cd: %s", err.Error())
+	}
+	return atomString(newDir)
+}
+
+func builtinPwd(env Env, _ ...Atom) Atom {
+	dir, err := env.scope.getwd()
+	if err != nil {
+		return lisherr("pwd: %s", err.Error())
+	}
+	return atomString(dir)
+}
+
+func builtinDirs(env Env, _ ...Atom) Atom {
+	wd := env.scope.wd()
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	dir, err := wd.getwd()
+	if err != nil {
+		return lisherr("dirs: %s", err.Error())
+	}
+
+	res := []Atom{atomString(dir)}
+	for i := len(wd.stack) - 1; i >= 0; i-- {
+		res = append(res, atomString(wd.stack[i]))
+	}
+	return atomList(res...)
+}
+
+func builtinPushd(env Env, args ...Atom) Atom {
+	dir, err := env.scope.getwd()
+	if err != nil {
+		return lisherr("pushd: %s", err.Error())
+	}
+
+	if _, err := env.scope.chdir(string(args[0].Value.(String))); err != nil {
+		return lisherr("pushd: %s", err.Error())
+	}
+
+	wd := env.scope.wd()
+	wd.mu.Lock()
+	wd.stack = append(wd.stack, dir)
+	wd.mu.Unlock()
+	return builtinDirs(env)
+}
+
+func builtinPopd(env Env, _ ...Atom) Atom {
+	wd := env.scope.wd()
+	wd.mu.Lock()
+	if len(wd.stack) == 0 {
+		wd.mu.Unlock()
+		return lisherr("popd: directory stack empty")
+	}
+	dir := wd.stack[len(wd.stack)-1]
+	wd.stack = wd.stack[:len(wd.stack)-1]
+	wd.mu.Unlock()
+
+	if _, err := env.scope.chdir(dir); err != nil {
+		return lisherr("popd: %s", err.Error())
+	}
+	return builtinDirs(env)
+}
@@ -0,0 +1,281 @@
+package interp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/rprtr258/fun"
+)
+
+// csvReader makes reader of comma or, if tsv is set, tab separated values
+func csvReader(text string, tsv bool) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(text))
+	if tsv {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	return r
+}
+
+// builtinCSVParse returns records as lists of strings, or as hashes keyed by
+// first record if :header is given
+func builtinCSVParse(args ...Atom) Atom {
+	opts, err := keywordFlags(args[1:], "header", "tsv")
+	if err != nil {
+		return lisherr("csv/parse: %s", err)
+	}
+
+	text, err := inputText(args[0])
+	if err != nil {
+		return lisherr("csv/parse: %s", err)
+	}
+
+	r := csvReader(text, opts["tsv"])
+	if !opts["header"] {
+		r.FieldsPerRecord = -1
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return lisherr("csv/parse: %s", err)
+	}
+
+	if !opts["header"] {
+		res := make([]Atom, len(records))
+		for i, record := range records {
+			res[i] = atomList(fun.Map[Atom](atomString[string], record...)...)
+		}
+		return atomList(res...)
+	}
+
+	if len(records) == 0 {
+		return atomNil
+	}
+	res := make([]Atom, len(records)-1)
+	for i, record := range records[1:] {
+		row := make(map[string]Atom, len(record))
+		for j, field := range record {
+			row[records[0][j]] = atomString(field)
+		}
+		res[i] = atomHash(row)
+	}
+	return atomList(res...)
+}
+
+// tableColumns returns sorted keys of all rows
+func tableColumns(rows []Atom) []string {
+	columns := []string{}
+	for _, row := range rows {
+		for k := range row.Value.(Hash) {
+			columns = append(columns, k)
+		}
+	}
+	slices.Sort(columns)
+	return slices.Compact(columns)
+}
+
+// builtinCSVWrite returns rows given as lists, or as hashes, written as csv.
+// Rows of hashes are preceded by header of their sorted keys.
+func builtinCSVWrite(args ...Atom) Atom {
+	opts, err := keywordFlags(args[1:], "tsv")
+	if err != nil {
+		return lisherr("csv/write: %s", err)
+	}
+
+	rows := args[0].Value.(List)
+	records := [][]string{}
+	switch {
+	case len(rows) == 0:
+	case isTable(rows):
+		columns := tableColumns(rows)
+		records = append(records, columns)
+		for _, row := range rows {
+			record := make([]string, len(columns))
+			for i, column := range columns {
+				if value, ok := row.Value.(Hash)[column]; ok {
+					record[i] = value.String()
+				}
+			}
+			records = append(records, record)
+		}
+	default:
+		for _, row := range rows {
+			if row.Kind != AtomKindList {
+				return lisherr("csv/write: expected rows to be lists or hashes, not %s", row)
+			}
+			records = append(records, fun.Map[string](Atom.String, row.Value.(List)...))
+		}
+	}
+
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if opts["tsv"] {
+		w.Comma = '\t'
+	}
+	if err := w.WriteAll(records); err != nil {
+		return lisherr("csv/write: %s", err)
+	}
+	return atomString(sb.String())
+}
+
+// isTable reports whether rows are all hashes, so they are shown as table
+func isTable(rows []Atom) bool {
+	return len(rows) > 0 && fun.All(func(row Atom) bool { return row.Kind == AtomKindHash }, rows...)
+}
+
+// span is range of runes column takes in line, end < 0 means rest of line
+type span struct{ start, end int }
+
+func (s span) cell(line []rune) string {
+	if s.start >= len(line) {
+		return ""
+	}
+	if s.end < 0 || s.end > len(line) {
+		return strings.TrimSpace(string(line[s.start:]))
+	}
+	return strings.TrimSpace(string(line[s.start:s.end]))
+}
+
+// builtinParseTable parses output of tools like ps or df: header line
+// followed by whitespace aligned columns. Columns are separated by positions
+// blank in every line, so right aligned values and header names like
+// "CONTAINER ID" stay in one column. Numbers become ints and floats.
+func builtinParseTable(args ...Atom) Atom {
+	text, err := inputText(args[0])
+	if err != nil {
+		return lisherr("parse-table: %s", err)
+	}
+
+	lines := [][]rune{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimRightFunc(line, unicode.IsSpace); line != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if len(lines) == 0 {
+		return atomNil
+	}
+	header, rows := lines[0], lines[1:]
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	blank := func(i int) bool {
+		return fun.All(func(line []rune) bool { return i >= len(line) || unicode.IsSpace(line[i]) }, lines...)
+	}
+
+	spans := []span{}
+	for i := 0; i < width; i++ {
+		switch {
+		case blank(i):
+		case len(spans) > 0 && spans[len(spans)-1].end == i:
+			spans[len(spans)-1].end++
+		default:
+			spans = append(spans, span{i, i + 1})
+		}
+	}
+
+	// join column without name, or with no values and separated by single
+	// space like "Mounted on" of df, to previous one
+	merged := []span{}
+	for _, s := range spans {
+		if len(merged) > 0 {
+			prev := &merged[len(merged)-1]
+			noValues := fun.All(func(row []rune) bool { return s.cell(row) == "" }, rows...)
+			if s.cell(header) == "" || noValues && s.start-prev.end == 1 {
+				prev.end = s.end
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	merged[len(merged)-1].end = -1
+
+	res := make([]Atom, len(rows))
+	for i, row := range rows {
+		hash := make(map[string]Atom, len(merged))
+		for _, s := range merged {
+			value := s.cell(row)
+			switch {
+			case _reInt.MatchString(value), _reFloat.MatchString(value):
+				hash[s.cell(header)] = readAtom(value)
+			default:
+				hash[s.cell(header)] = atomString(value)
+			}
+		}
+		res[i] = atomHash(hash)
+	}
+	return atomList(res...)
+}
+
+// formatTable lays out rows of hashes as table with column per key, returns
+// false if table does not fit in width
+func formatTable(rows []Atom, width int) (string, bool) {
+	more := 0
+	if len(rows) > pprintMaxItems {
+		rows, more = rows[:pprintMaxItems], len(rows)-pprintMaxItems
+	}
+
+	columns := tableColumns(rows)
+	cells := make([][]string, len(rows))
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = len([]rune(column))
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(columns))
+		for j, column := range columns {
+			value, ok := row.Value.(Hash)[column]
+			if !ok {
+				continue
+			}
+			cell := value.String()
+			if strings.Contains(cell, "\n") || value.Kind == AtomKindList || value.Kind == AtomKindHash {
+				cell = prStr(value)
+			}
+			cells[i][j] = cell
+			widths[j] = max(widths[j], len([]rune(cell)))
+		}
+	}
+
+	total := 0
+	for _, w := range widths {
+		total += w + 2
+	}
+	if total-2 > width {
+		return "", false
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string, isNumber func(int) bool) {
+		line := make([]string, len(row))
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+			line[i] = cell + pad
+			if isNumber(i) {
+				line[i] = pad + cell
+			}
+		}
+		sb.WriteString(strings.TrimRight(strings.Join(line, "  "), " ") + "\n")
+	}
+
+	writeRow(columns, func(int) bool { return false })
+	dashes := make([]string, len(columns))
+	for i, w := range widths {
+		dashes[i] = strings.Repeat("-", w)
+	}
+	writeRow(dashes, func(int) bool { return false })
+	for i, row := range rows {
+		writeRow(cells[i], func(j int) bool {
+			value := row.Value.(Hash)[columns[j]]
+			return value.Kind == AtomKindInt || value.Kind == AtomKindFloat
+		})
+	}
+	if more > 0 {
+		fmt.Fprintf(&sb, "...(%d more)\n", more)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
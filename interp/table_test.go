@@ -0,0 +1,97 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSV(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"parse": {`(csv/parse "a,b\n1,\"x,y\"\n")`, atomList(
+			atomList(atomString("a"), atomString("b")),
+			atomList(atomString("1"), atomString("x,y")),
+		)},
+		"parse_header": {`(csv/parse "a,b\n1,2\n" :header)`, atomList(
+			atomHash(map[string]Atom{"a": atomString("1"), "b": atomString("2")}),
+		)},
+		"parse_tsv":           {`(csv/parse "a\tb c\n" :tsv)`, atomList(atomList(atomString("a"), atomString("b c")))},
+		"parse_header_ragged": {`(csv/parse "a,b\n1\n" :header)`, lisherr("csv/parse: record on line 2: wrong number of fields")},
+		"write":               {`(csv/write (list (list "a" 1) (list "b,c" :d)))`, atomString("a,1\n\"b,c\",:d\n")},
+		"write_hashes":        {`(csv/write (list {:b 2 :a 1} {:c 3}))`, atomString("a,b,c\n1,2,\n,,3\n")},
+		"write_tsv":           {`(csv/write (list (list "a" "b")) :tsv)`, atomString("a\tb\n")},
+		"write_invalid":       {`(csv/write (list 1))`, lisherr("csv/write: expected rows to be lists or hashes, not 1")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(read(tc.input), newEnvRepl()))
+		})
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	row := func(kvs ...any) Atom {
+		res := map[string]Atom{}
+		for i := 0; i < len(kvs); i += 2 {
+			switch v := kvs[i+1].(type) {
+			case int:
+				res[kvs[i].(string)] = atomInt(v)
+			case string:
+				res[kvs[i].(string)] = atomString(v)
+			}
+		}
+		return atomHash(res)
+	}
+
+	for name, tc := range map[string]struct {
+		text string
+		res  Atom
+	}{
+		"ps": {`
+    PID TTY          TIME CMD
+      7 pts/0    00:00:00 bash
+  12345 pts/0    00:00:01 sleep 100
+`, atomList(
+			row("PID", 7, "TTY", "pts/0", "TIME", "00:00:00", "CMD", "bash"),
+			row("PID", 12345, "TTY", "pts/0", "TIME", "00:00:01", "CMD", "sleep 100"),
+		)},
+		"df": {`
+Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        50G   20G   30G  40% /
+tmpfs           1.0G     0  1.0G   0% /my disk
+`, atomList(
+			row("Filesystem", "/dev/sda1", "Size", "50G", "Used", "20G", "Avail", "30G", "Use%", "40%", "Mounted on", "/"),
+			row("Filesystem", "tmpfs", "Size", "1.0G", "Used", 0, "Avail", "1.0G", "Use%", "0%", "Mounted on", "/my disk"),
+		)},
+		"docker": {`
+CONTAINER ID   IMAGE     STATUS         PORTS
+3f4e5a6b7c8d   nginx     Up 2 minutes
+`, atomList(
+			row("CONTAINER ID", "3f4e5a6b7c8d", "IMAGE", "nginx", "STATUS", "Up 2 minutes", "PORTS", ""),
+		)},
+		"empty": {"\n", atomNil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repl_env := newEnvRepl()
+			repl_env.set("text", atomString(tc.text))
+			assert.Equal(t, tc.res, eval(read(`(parse-table text)`), repl_env))
+		})
+	}
+}
+
+func TestFormatTable(t *testing.T) {
+	rows := eval(read(`(list {:name "lish" :size 120} {:name "a b" :size 7 :tags (list 1)})`), newEnvRepl()).Value.(List)
+
+	table, ok := formatTable(rows, 80)
+	assert.True(t, ok)
+	assert.Equal(t, ""+
+		"name  size  tags\n"+
+		"----  ----  ----\n"+
+		"lish   120\n"+
+		"a b      7  (1)", table)
+
+	_, ok = formatTable(rows, 10)
+	assert.False(t, ok)
+}
@@ -0,0 +1,53 @@
+package interp
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrognSingleEvaluation(t *testing.T) {
+	// value put second is taken second only if the first one is put once
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"progn": {`(let (c (chan 3)) (progn (put! c 1)) (put! c 2) (take! c) (take! c))`, atomInt(2)},
+		"let":   {`(let (c (chan 3)) (let () (put! c 1)) (put! c 2) (take! c) (take! c))`, atomInt(2)},
+		"empty": {`(progn)`, atomNil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.res, eval(readValue(tc.input), newEnvRepl()))
+			assert.Equal(t, tc.res, evalCompiled(readValue(tc.input), newEnvRepl()))
+		})
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// calls in tail position must not grow Go stack, so deep recursion
+	// fails with fatal stack overflow if they do
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	for name, tc := range map[string]struct {
+		input string
+		n     int
+	}{
+		"if":     {`(defun f (n) (if (= n 0) :done (f (- n 1))))`, 1_000_000},
+		"mutual": {`(progn (defun f (n) (if (= n 0) :done (g (- n 1)))) (defun g (n) (if (= n 0) :done (f (- n 1)))))`, 10_000},
+		"let":    {`(defun f (n) (if (= n 0) :done (let (m (- n 1)) (f m))))`, 10_000},
+		"progn":  {`(defun f (n) (if (= n 0) :done (progn (+ n 1) (f (- n 1)))))`, 10_000},
+		"apply":  {`(defun f (n) (if (= n 0) :done (apply f (- n 1))))`, 10_000},
+		"cond":   {`(defun f (n) (cond (= n 0) :done :else (f (- n 1))))`, 10_000},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newEnvRepl()
+			assert.NotEqual(t, AtomKindError, builtinLoadFile(env, atomString("../compose.lish")).Kind)
+			assert.NotEqual(t, AtomKindError, eval(readValue(tc.input), env).Kind)
+
+			call := atomList(atomSymbol("f"), atomInt(tc.n))
+			assert.Equal(t, atomKeyword("done"), eval(call, env))
+			assert.Equal(t, atomKeyword("done"), evalCompiled(call, env))
+		})
+	}
+}
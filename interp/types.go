@@ -0,0 +1,151 @@
+package interp
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+)
+
+const (
+	AtomKindBool    AtomKind = "bool"
+	AtomKindInt     AtomKind = "int64"
+	AtomKindFloat   AtomKind = "f64"
+	AtomKindString  AtomKind = "string"
+	AtomKindKeyword AtomKind = "keyword"
+	AtomKindError   AtomKind = "error"
+	AtomKindHash    AtomKind = "hash"
+	AtomKindStream  AtomKind = "stream"
+	AtomKindFuture  AtomKind = "future"
+	AtomKindRecord  AtomKind = "record"
+)
+
+type Bool bool
+
+func (s Bool) String() string   { return fmt.Sprint(bool(s)) }
+func (s Bool) GoString() string { return fmt.Sprint(bool(s)) }
+func (v Bool) Cmp(other Value) int {
+	va := bool(v)
+	vb := bool(other.(Bool))
+	switch {
+	case !va && vb: // false < true
+		return -1
+	case va && !vb: // true > false
+		return 1
+	default:
+		return 0
+	}
+}
+
+type Int int64
+
+func (s Int) String() string      { return fmt.Sprint(int64(s)) }
+func (s Int) GoString() string    { return fmt.Sprint(int64(s)) }
+func (s Int) Cmp(other Value) int { return cmp.Compare(s, other.(Int)) }
+
+type Float float64
+
+func (s Float) String() string      { return formatFloat(float64(s)) }
+func (s Float) GoString() string    { return formatFloat(float64(s)) }
+func (s Float) Cmp(other Value) int { return cmp.Compare(s, other.(Float)) }
+
+type String string
+
+func (s String) String() string      { return string(s) }
+func (s String) GoString() string    { return strconv.Quote(string(s)) }
+func (s String) Cmp(other Value) int { return cmp.Compare(s, other.(String)) }
+
+// Keyword is a symbol starting with colon which evaluates to itself
+type Keyword string
+
+func (s Keyword) String() string      { return ":" + string(s) }
+func (s Keyword) GoString() string    { return ":" + string(s) }
+func (s Keyword) Cmp(other Value) int { return cmp.Compare(s, other.(Keyword)) }
+
+type Error string
+
+func (s Error) String() string      { return "ERROR: " + strconv.Quote(string(s)) }
+func (s Error) GoString() string    { return "ERROR: " + strconv.Quote(string(s)) }
+func (s Error) Cmp(other Value) int { return cmp.Compare(s, other.(Error)) }
+
+// Error is also Go error, so that embedding program gets error atom as is
+func (s Error) Error() string { return string(s) }
+
+type Hash map[string]Atom
+
+func (v Hash) String() string   { return prStr(atomHash(v)) }
+func (v Hash) GoString() string { return prStr(atomHash(v)) }
+
+// Cmp compares hashes as lists of keys and values sorted by keys
+func (v Hash) Cmp(other Value) int {
+	return List(items(atomHash(v))).Cmp(List(items(atomHash(other.(Hash)))))
+}
+
+type Stream chan Atom
+
+func (s Stream) String() string      { return "#stream" }
+func (v Stream) GoString() string    { return fmt.Sprintf("#stream@%v", v) }
+func (s Stream) Cmp(other Value) int { return cmpIdentity(s, other.(Stream)) }
+
+// Future is value of expression evaluated in goroutine started by go
+type Future struct {
+	done chan struct{} // closed once res is set
+	res  *Atom
+}
+
+func (f Future) String() string      { return "#future" }
+func (f Future) GoString() string    { return fmt.Sprintf("#future@%v", f.done) }
+func (f Future) Cmp(other Value) int { return cmpIdentity(f.done, other.(Future).done) }
+
+func atomString[T ~string](s T) Atom {
+	return Atom{AtomKindString, String(s)}
+}
+
+func atomKeyword[T ~string](s T) Atom {
+	return Atom{AtomKindKeyword, Keyword(s)}
+}
+
+func atomBool[T ~bool](b T) Atom {
+	return Atom{AtomKindBool, Bool(b)}
+}
+
+func atomHash(m map[string]Atom) Atom {
+	return Atom{AtomKindHash, Hash(m)}
+}
+
+func atomInt[T interface {
+	int | uint | int8 | uint8 | int16 | uint16 | int32 | uint32 | int64 | uint64
+}](n T) Atom {
+	return Atom{AtomKindInt, Int(n)}
+}
+
+func atomFloat[T interface {
+	float32 | float64
+}](x T) Atom {
+	return Atom{AtomKindFloat, Float(x)}
+}
+
+func lisherr(format string, args ...any) Atom {
+	return Atom{AtomKindError, Error(fmt.Sprintf(format, args...))}
+}
+
+// isCommandResult reports whether a is a hash returned by external command
+func isCommandResult(a Atom) bool {
+	if a.Kind != AtomKindHash {
+		return false
+	}
+	code, ok := a.Value.(Hash)["exit_code"]
+	return ok && code.Kind == AtomKindInt
+}
+
+// truthy reports whether a is true in conditions. Only false and results of
+// failed commands are falsy.
+func truthy(a Atom) bool {
+	switch {
+	case a.Kind == AtomKindBool:
+		return bool(a.Value.(Bool))
+	case isCommandResult(a):
+		return a.Value.(Hash)["exit_code"].Value.(Int) == 0
+	default:
+		return true
+	}
+}
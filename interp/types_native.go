@@ -0,0 +1,269 @@
+package interp
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+type AtomKind string
+
+const (
+	AtomKindSymbol AtomKind = "symbol"
+	AtomKindFunc   AtomKind = "fn"
+	AtomKindLambda AtomKind = "lambda"
+	AtomKindList   AtomKind = "list"
+)
+
+type Symbol string
+
+func (s Symbol) String() string   { return string(s) }
+func (s Symbol) GoString() string { return string(s) }
+func (s Symbol) Cmp(other Value) int {
+	return cmp.Compare(s, other.(Symbol))
+}
+
+// Meta describes function for doc, apropos and source
+type Meta struct {
+	name   string
+	params string // argument list, like (x & xs)
+	doc    string
+	pos    string // where function is defined, as file:line
+}
+
+type Func struct {
+	fn   func(Env, []Atom) Atom
+	meta *Meta
+	// compiled lambda fn runs, nil for builtins
+	closure *closure
+	// kind of records constructor of which fn is
+	record *recordType
+	// implementations of protocol method fn is
+	generic *genericFunc
+}
+
+func (s Func) String() string   { return "#fn" }
+func (s Func) GoString() string { return fmt.Sprintf("fn@%p", s.fn) }
+
+// Cmp orders functions by identity, closures of the same fn form are distinct.
+// Meta is copied once function is named, so it is identity of builtins only,
+// which are named before evaluated code gets them.
+func (s Func) Cmp(other Value) int {
+	o := other.(Func)
+	if s.closure != nil || o.closure != nil {
+		return cmpIdentity(s.closure, o.closure)
+	}
+	return cmpIdentity(s.meta, o.meta)
+}
+
+func (s Func) call(env Env, args []Atom) Atom {
+	return s.fn(env, args)
+}
+
+type Lambda struct {
+	eval    func(ast Atom, env Env) Atom
+	ast     Atom
+	env     Env
+	params  []Atom // patterns arguments are bound to
+	isMacro bool
+	meta    *Meta
+	arity   arity
+	// clauses of multi-arity lambda, the first one taking number of arguments
+	// is called. Body and params are of the first clause.
+	clauses []Lambda
+	// allocated once fn is evaluated, lambdas are equal if it is the same
+	id *byte
+}
+
+func (v Lambda) String() string   { return prStr(atomLambda(v)) }
+func (v Lambda) GoString() string { return prStr(atomLambda(v)) }
+
+// Cmp orders lambdas by identity, macro made of lambda differs from it
+func (v Lambda) Cmp(other Value) int {
+	o := other.(Lambda)
+	if c := cmpIdentity(v.id, o.id); c != 0 {
+		return c
+	}
+	return Bool(v.isMacro).Cmp(Bool(o.isMacro))
+}
+
+type List []Atom                // List, Nil if empty
+func (v List) String() string   { return prStr(Atom{AtomKindList, v}) }
+func (v List) GoString() string { return prStr(Atom{AtomKindList, v}) }
+func (va List) Cmp(other Value) int {
+	vb := other.(List)
+	for i := 0; i < min(len(va), len(vb)); i++ {
+		if c := atomCmp(va[i], vb[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(va), len(vb))
+}
+
+type Value interface {
+	fmt.Stringer
+	fmt.GoStringer
+	// compare, given two atoms of same kind
+	// return -1 if less, 0 if equal, 1 if greater
+	Cmp(Value) int
+}
+
+type Atom struct {
+	Kind  AtomKind
+	Value Value
+}
+
+var atomNil = Atom{AtomKindList, List(nil)}
+
+func (a Atom) String() string   { return a.Value.String() }
+func (a Atom) GoString() string { return a.Value.GoString() }
+
+func atomSymbol(s string) Atom {
+	return Atom{AtomKindSymbol, Symbol(s)}
+}
+
+func atomList(list ...Atom) Atom {
+	if len(list) == 0 {
+		return atomNil
+	}
+
+	return Atom{AtomKindList, List(list)}
+}
+
+type funcValidator = func([]Atom) (string, bool)
+
+// param describes argument of builtin, any kind is accepted if kinds are empty
+type param struct {
+	kinds    []AtomKind
+	optional bool
+	variadic bool
+}
+
+// arg is required argument
+func arg(kinds ...AtomKind) param {
+	return param{kinds: kinds}
+}
+
+// optional argument might be omitted, it goes after required ones
+func optional(kinds ...AtomKind) param {
+	return param{kinds: kinds, optional: true}
+}
+
+// variadic matches any number of remaining arguments, it goes last
+func variadic(kinds ...AtomKind) param {
+	return param{kinds: kinds, variadic: true}
+}
+
+func (p param) accepts(a Atom) bool {
+	return len(p.kinds) == 0 || slices.Contains(p.kinds, a.Kind)
+}
+
+// signature makes validator checking number and kinds of arguments against
+// params
+func signature(params ...param) funcValidator {
+	required, optionals := 0, 0
+	rest := fun.Invalid[param]()
+	for i, p := range params {
+		switch {
+		case p.variadic:
+			if i != len(params)-1 {
+				panic("variadic param must be last")
+			}
+			rest = fun.Valid(p)
+		case p.optional:
+			optionals++
+		default:
+			if optionals > 0 {
+				panic("required param after optional one")
+			}
+			required++
+		}
+	}
+	positional := params[:required+optionals]
+
+	var arity string
+	switch {
+	case rest.Valid:
+		arity = fmt.Sprintf("Expected at least %d arguments", required)
+	case optionals == 0:
+		arity = fmt.Sprintf("Expected exactly %d arguments", required)
+	default:
+		arity = fmt.Sprintf("Expected from %d to %d arguments", required, required+optionals)
+	}
+
+	return func(args []Atom) (string, bool) {
+		if len(args) < required || !rest.Valid && len(args) > len(positional) {
+			return arity, false
+		}
+
+		for i, a := range args {
+			p := rest.Value
+			if i < len(positional) {
+				p = positional[i]
+			}
+			if !p.accepts(a) {
+				kinds := fun.Map[string](func(k AtomKind) string { return string(k) }, p.kinds...)
+				return fmt.Sprintf(
+					"Expected %d-th argument to be %s, not %s",
+					i, strings.Join(kinds, " or "), a.Kind,
+				), false
+			}
+		}
+		return "", true
+	}
+}
+
+func atomLambda(lambda Lambda) Atom {
+	return Atom{AtomKindLambda, lambda}
+}
+
+func atomFunc(
+	fn func(...Atom) Atom,
+	validators ...funcValidator,
+) Atom {
+	return atomFuncEnv(func(_ Env, args ...Atom) Atom {
+		return fn(args...)
+	}, validators...)
+}
+
+// atomFuncEnv makes builtin which also gets environment it is called from
+func atomFuncEnv(
+	fn func(Env, ...Atom) Atom,
+	validators ...funcValidator,
+) Atom {
+	return Atom{AtomKindFunc, Func{fn: func(env Env, args []Atom) Atom {
+		for _, v := range validators {
+			if msg, ok := v(args); !ok {
+				return lisherr("%s, but got %s", msg, strings.Join(fun.Map[string](Atom.String, args...), " "))
+			}
+		}
+
+		return fn(env, args...)
+	}, meta: &Meta{}}}
+}
+
+// documented annotates builtin with its argument list and docstring, position
+// of the call is remembered as builtin source
+func documented(params, doc string, fn Atom) Atom {
+	meta := fn.Value.(Func).meta
+	meta.params, meta.doc = params, doc
+	if _, file, line, ok := runtime.Caller(1); ok {
+		meta.pos = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return fn
+}
+
+func atomFuncNil(
+	body func(...Atom),
+	validators ...funcValidator,
+) Atom {
+	return atomFunc(func(args ...Atom) Atom {
+		body(args...)
+		return atomNil
+	}, validators...)
+}
@@ -0,0 +1,257 @@
+package interp
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/rprtr258/fun"
+)
+
+// frame holds local variables of single call of compiled function
+type frame struct {
+	slots []Atom
+	// frame of function closure was made in
+	parent *frame
+}
+
+// closure is compiled lambda along with frame it captures
+type closure struct {
+	proto  *proto
+	parent *frame
+	// env is environment globals are looked up in
+	env Env
+}
+
+func (f *frame) up(depth int) *frame {
+	for ; depth > 0; depth-- {
+		f = f.parent
+	}
+	return f
+}
+
+// callInfo is state of function being run by vm
+type callInfo struct {
+	proto *proto
+	frame *frame
+	env   Env
+	pc    int
+	// stack height when function was called
+	base int
+}
+
+// newCall binds arguments of closure call, shell scope is taken from the
+// caller as eval does
+func newCall(fn Func, args []Atom, caller Env, base int) (callInfo, Atom, bool) {
+	c, p := fn.closure, fn.closure.proto
+	if want := (arity{p.params, fun.IF(p.rest, -1, p.params)}); !want.accepts(len(args)) {
+		return callInfo{}, arityError(fn.meta, want, len(args)), false
+	}
+
+	slots := make([]Atom, p.slots)
+	for i := range slots {
+		slots[i] = atomNil
+	}
+	copy(slots, args[:p.params])
+	if p.rest {
+		// args might be slice of vm stack
+		slots[p.params] = atomList(slices.Clone(args[p.params:])...)
+	}
+
+	env := c.env
+	env.scope = caller.scope
+	return callInfo{p, &frame{slots, c.parent}, env, 0, base}, Atom{}, true
+}
+
+// makeClosure makes function of compiled lambda, it is called by vm directly
+// and by others through Func
+func makeClosure(p *proto, parent *frame, env Env) Atom {
+	res := Func{meta: p.meta, closure: &closure{p, parent, env}}
+	res.fn = func(caller Env, args []Atom) Atom {
+		ci, err, ok := newCall(res, args, caller, 0)
+		if !ok {
+			return err
+		}
+		return run(ci)
+	}
+	return Atom{AtomKindFunc, res}
+}
+
+// evalCompiled evaluates form by compiling it into bytecode, forms compiler
+// does not support are evaluated by eval
+func evalCompiled(ast Atom, env Env) Atom {
+	p, err := compileTop(ast, env)
+	if err != nil {
+		return eval(ast, env)
+	}
+
+	slots := make([]Atom, p.slots)
+	for i := range slots {
+		slots[i] = atomNil
+	}
+	return run(callInfo{p, &frame{slots, nil}, env, 0, 0})
+}
+
+// evalForm evaluates top level form, compiling it if scope says so
+func evalForm(ast Atom, env Env) Atom {
+	if env.scope != nil && env.scope.bytecode {
+		return evalCompiled(ast, env)
+	}
+	return eval(ast, env)
+}
+
+// callValue calls function which is not compiled closure
+func callValue(fn Atom, args []Atom, env Env) Atom {
+	switch fn.Kind {
+	case AtomKindLambda, AtomKindFunc:
+		return call(fn, args, env)
+	case AtomKindString:
+		return runCommand(env, string(fn.Value.(String)), args)
+	case AtomKindHash:
+		return callHash(fn.Value.(Hash), args)
+	default:
+		return lisherr("%s is not a function", fn)
+	}
+}
+
+// run runs compiled function until it returns. Calls of compiled closures
+// are run in the same loop, so that they do not grow Go stack, and tail calls
+// reuse stack space of caller.
+func run(ci callInfo) Atom {
+	stack := make([]Atom, 0, 16)
+	calls := []callInfo{}
+	push := func(a Atom) { stack = append(stack, a) }
+	pop := func() Atom {
+		a := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return a
+	}
+
+	for {
+		in := ci.proto.code[ci.pc]
+		ci.pc++
+		switch in.op {
+		case opConst:
+			push(ci.proto.consts[in.a])
+		case opLocal:
+			push(ci.frame.up(in.a).slots[in.b])
+		case opSetLocal:
+			ci.frame.up(in.a).slots[in.b] = stack[len(stack)-1]
+		case opGlobal:
+			push(evalSymbol(ci.proto.consts[in.a].Value.(Symbol), ci.env))
+		case opCallee:
+			s := ci.proto.consts[in.a].Value.(Symbol)
+			fn, ok := lookup(ci.env, s)
+			if !ok {
+				fn = atomString(s)
+			}
+			push(fn)
+		case opSetGlobal:
+			ci.env.set(ci.proto.consts[in.a].Value.(Symbol), stack[len(stack)-1])
+		case opName:
+			stack[len(stack)-1] = named(stack[len(stack)-1], ci.proto.consts[in.a].Value.(Symbol))
+		case opPop:
+			pop()
+		case opJump:
+			ci.pc = in.a
+		case opJumpIfFalse:
+			if !truthy(pop()) {
+				ci.pc = in.a
+			}
+		case opJumpIfError:
+			if stack[len(stack)-1].Kind == AtomKindError {
+				ci.pc = in.a
+			}
+		case opJumpIfDecided:
+			if truthy(stack[len(stack)-1]) == (in.b == 1) {
+				ci.pc = in.a
+			} else {
+				pop()
+			}
+		case opHash:
+			values := stack[len(stack)-in.a:]
+			keys := ci.proto.consts[in.b].Value.(List)
+			res, h := Atom{}, make(map[string]Atom, in.a)
+			for i, v := range values {
+				if v.Kind == AtomKindError {
+					res = v
+					break
+				}
+				h[string(keys[i].Value.(String))] = v
+			}
+			if res.Kind == "" {
+				res = atomHash(h)
+			}
+			stack = stack[:len(stack)-in.a]
+			push(res)
+		case opClosure:
+			push(makeClosure(ci.proto.protos[in.a], ci.frame, ci.env))
+		case opCall, opTailCall:
+			// long running loops stop on timeout or interrupt
+			if cancelled, ok := ci.env.scope.cancelled(); ok {
+				return cancelled
+			}
+			if exceeded, ok := ci.env.scope.step(); ok {
+				return exceeded
+			}
+
+			fnIndex := len(stack) - in.a - 1
+			fn, args := stack[fnIndex], stack[fnIndex+1:]
+			for {
+				// function apply calls is called by vm directly
+				f, ok := applied(fn, args)
+				if !ok {
+					break
+				}
+				fn, args = f, args[1:]
+			}
+			var res Atom
+			if f, ok := fn.Value.(Func); ok && f.closure != nil {
+				callee, err, ok := newCall(f, args, ci.env, fnIndex)
+				if ok {
+					if in.op == opTailCall {
+						callee.base = ci.base
+					} else {
+						calls = append(calls, ci)
+					}
+					stack = stack[:callee.base]
+					ci = callee
+					continue
+				}
+				res = err
+			} else {
+				res = callValue(fn, slices.Clone(args), ci.env)
+			}
+			stack = stack[:fnIndex]
+			push(res)
+			if in.op == opCall {
+				continue
+			}
+			fallthrough
+		case opReturn:
+			res := pop()
+			stack = stack[:ci.base]
+			if len(calls) == 0 {
+				return res
+			}
+			ci, calls = calls[len(calls)-1], calls[:len(calls)-1]
+			push(res)
+		}
+	}
+}
+
+// evalSymbol returns value bound to symbol as eval does: environment
+// variable if it starts with $, name itself if it is unbound
+func evalSymbol(s Symbol, env Env) Atom {
+	if res, ok := lookup(env, s); ok {
+		return res
+	}
+
+	// $NAME reads environment variable, nil if it is unset
+	if name, isVar := strings.CutPrefix(string(s), "$"); isVar && name != "" {
+		if value, ok := env.scope.getenv(name); ok {
+			return atomString(value)
+		}
+		return atomNil
+	}
+	return atomString(s)
+}
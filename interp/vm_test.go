@@ -0,0 +1,87 @@
+package interp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const vmTestPrelude = `
+(setmacro defun (fn (f args & body) ` + "`" + `(set ,f (fn ,args ,@body))))
+(defun fib (n) (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2)))))
+(defun sum-to (n acc) (if (= n 0) acc (sum-to (- n 1) (+ acc n))))
+(defun depth (n) (if (= n 0) 0 (+ 1 (depth (- n 1)))))
+(defun total (xs acc) (if (empty? xs) acc (total (rest xs) (+ acc (first xs)))))
+(defun repeat (x n acc) (if (= n 0) acc (repeat x (- n 1) (cons x acc))))
+`
+
+func TestCompiled(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		res   Atom
+	}{
+		"const":      {`42`, atomInt(42)},
+		"call":       {`(+ 1 (* 2 3))`, atomInt(7)},
+		"if":         {`(if (< 1 2) :yes :no)`, atomKeyword("yes")},
+		"if_nil":     {`(if false 1)`, atomNil},
+		"and":        {`(and 1 false (throw "not evaluated"))`, atomBool(false)},
+		"or":         {`(or false 2 (throw "not evaluated"))`, atomInt(2)},
+		"let":        {`(let (a 1 b (+ a 1)) (* a b 10))`, atomInt(20)},
+		"shadow":     {`(let (+ (fn (a b) (* a b))) (+ 3 4))`, atomInt(12)},
+		"closure":    {`(let (add (fn (a) (fn (b) (+ a b)))) ((add 1) 2))`, atomInt(3)},
+		"rest":       {`((fn (a & xs) (cons a xs)) 1 2 3)`, atomList(atomInt(1), atomInt(2), atomInt(3))},
+		"hash":       {`((let (a 1) {:a a :b (+ a 1)}) :b)`, atomInt(2)},
+		"quote":      {`'(a b)`, atomList(atomSymbol("a"), atomSymbol("b"))},
+		"quasiquote": {"(let (x 1) `(a ,x))", atomList(atomSymbol("a"), atomInt(1))},
+		"macro":      {`(progn (defun sq (x) (* x x)) (sq 5))`, atomInt(25)},
+		"recursion":  {`(fib 15)`, atomInt(610)},
+		"tail_calls": {`(sum-to 100000 0)`, atomInt(5000050000)},
+		"deep_calls": {`(depth 100000)`, atomInt(100000)},
+		"set":        {`((fn (x) (progn (set y (* x 2)) (+ y 1))) 5)`, atomInt(11)},
+		"let_rec":    {`(let (f (fn (n) (if (= n 0) :done (f (- n 1))))) (f 10))`, atomKeyword("done")},
+		"let_outer":  {`(let (x 1) (let (x (+ x 1)) x))`, atomInt(2)},
+		"fn_equal":   {`(let (h (fn (x) x) mk (fn () (fn (x) x))) (progn (set k h) (list (= h k) (= (mk) (mk)))))`, atomList(atomBool(true), atomBool(false))},
+		"builtin":    {`(pmap fib '(10 11))`, atomList(atomInt(55), atomInt(89))},
+		"hash_call":  {`({:a 1} :a)`, atomInt(1)},
+		"command":    {`((sh "-c" "echo hi") :stdout)`, atomString("hi\n")},
+		"fallback":   {`(with-env {"X" "1"} $X)`, atomString("1")},
+		"error":      {`(let (a (throw "oops")) (+ a 1))`, lisherr("oops")},
+		"error_arg":  {`(cancelled? (throw "oops"))`, atomBool(false)},
+		"arity":      {`(fib)`, lisherr("fib requires 1 argument(s), but got 0")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newEnvRepl()
+			env.scope.bytecode = true
+			assert.NotEqual(t, AtomKindError, evalProgram(vmTestPrelude, env).Kind)
+			assert.Equal(t, tc.res, evalCompiled(readValue(tc.input), env))
+		})
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	for _, bench := range []struct {
+		name  string
+		input string
+	}{
+		{"fib", `(fib 20)`},
+		{"tail_calls", `(sum-to 10000 0)`},
+		{"lists", `(total (repeat 7 1000 (quote ())) 0)`},
+		{"closures", `(let (add (fn (a) (fn (b) (+ a b)))) ((add 1) 2))`},
+	} {
+		for _, evaluator := range []string{"tree", "bytecode"} {
+			b.Run(bench.name+"/"+evaluator, func(b *testing.B) {
+				env := newEnvRepl()
+				env.scope.bytecode = evaluator == "bytecode"
+				if res := evalProgram(vmTestPrelude, env); res.Kind == AtomKindError {
+					b.Fatal(res)
+				}
+				form := readValue(bench.input)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					evalForm(form, env)
+				}
+			})
+		}
+	}
+}
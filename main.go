@@ -1,18 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/chzyer/readline"
-	"github.com/rprtr258/fun"
+
+	"github.com/rprtr258/lish/interp"
 )
 
 const HISTORY_FILE = ".lish_history"
 
-// TODO: implement completer
-type autocomplete struct{}
+// autocomplete completes file paths relative to current directory and
+// symbols defined in environment
+type autocomplete struct {
+	in *interp.Interp
+}
 
 // impl Hinter for LishHelper {
 //     type Hint = String;
@@ -30,32 +40,139 @@ type autocomplete struct{}
 //	    }
 //	}
 func (self autocomplete) Do(line []rune, pos int) (newLine [][]rune, length int) {
-	return [][]rune{[]rune("pokus"), []rune("fokus")}, 0
+	return self.in.Complete(line, pos)
+}
+
+// interruptible returns context cancelled on interrupt, so that Ctrl-C stops
+// evaluation and commands it runs instead of lish itself. stop must be called
+// once evaluation is done.
+func interruptible() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel(errors.New("interrupted"))
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+// statusOf returns exit code of lish evaluated to res
+func statusOf(res interp.Atom, err error) int {
+	var exit *interp.ExitError
+	switch {
+	case errors.As(err, &exit):
+		return exit.Code
+	case err != nil:
+		return 1
+	default:
+		return interp.Status(res)
+	}
 }
 
-func run() error {
-	editor, _ := readline.NewEx(&readline.Config{
-		Prompt:       "=> ",
+// parseOptions parses options given before -c or script path, returning
+// interpreter options and the rest of args
+func parseOptions(args []string) ([]interp.Option, []string, error) {
+	opts := []interp.Option{}
+	sandbox, sandboxed := interp.Sandbox{}, false
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		args = args[1:]
+		if name == "--" {
+			break
+		}
+
+		if name != "--sandbox" && name != "--bytecode" && !hasValue {
+			if len(args) == 0 {
+				return nil, nil, fmt.Errorf("%s requires an argument", name)
+			}
+			value, args = args[0], args[1:]
+		}
+
+		var err error
+		switch name {
+		case "--bytecode":
+			opts = append(opts, interp.WithBytecode())
+			continue
+		case "--sandbox":
+			sandbox.NoExec, sandbox.NoFiles = true, true
+		case "--allow-exec":
+			sandbox.Exec = append(sandbox.Exec, strings.Split(value, ",")...)
+		case "--allow-read":
+			sandbox.Read = append(sandbox.Read, filepath.SplitList(value)...)
+		case "--max-steps":
+			sandbox.MaxSteps, err = strconv.Atoi(value)
+		case "--max-memory":
+			sandbox.MaxMemory, err = strconv.ParseUint(value, 10, 64)
+		default:
+			return nil, nil, fmt.Errorf("unknown option %s", name)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sandboxed = true
+	}
+
+	if sandboxed {
+		opts = append(opts, interp.WithSandbox(sandbox))
+	}
+	return opts, args, nil
+}
+
+func run() (int, error) {
+	opts, args, err := parseOptions(os.Args[1:])
+	if err != nil {
+		return 2, err
+	}
+	opts = append(opts, interp.WithWarnings(os.Stderr))
+
+	switch {
+	case len(args) > 0 && args[0] == "-c":
+		// lish -c expr args...
+		if len(args) < 2 {
+			return 2, errors.New("-c requires an argument")
+		}
+
+		in := interp.New(append(opts, interp.WithArgs(args[2:]...))...)
+		res, err := in.EvalLine(context.Background(), args[1])
+		if err != nil {
+			if res.Kind == interp.AtomKindError {
+				fmt.Fprintln(os.Stderr, res)
+			}
+		} else if s, ok := interp.Display(res); ok {
+			fmt.Println(s)
+		}
+		return statusOf(res, err), nil
+	case len(args) > 0:
+		// lish script args...
+		in := interp.New(append(opts, interp.WithArgs(args[1:]...))...)
+		res, err := in.EvalFile(context.Background(), args[0])
+		if err != nil && res.Kind == interp.AtomKindError {
+			fmt.Fprintln(os.Stderr, res)
+		}
+		return statusOf(res, err), nil
+	}
+
+	in := interp.New(opts...)
+	editor, err := readline.NewEx(&readline.Config{
+		Prompt:       in.Prompt(),
 		HistoryFile:  HISTORY_FILE,
-		AutoComplete: autocomplete{},
+		AutoComplete: autocomplete{in},
 	})
-	defer editor.Close()
-
-	replEnv := newEnvRepl()
-	cmdArgs := os.Args
-	replEnv.set("*ARGV*", atomList(fun.Map[Atom](func(s string) Atom { return atomString(s) }, os.Args...)...))
-	// TODO: rename to load ?
-	// TODO: detect error
-	rep(`(set load-file (fn (f) (eval (read (join "(progn\n" (slurp f) "\n)")))))`, replEnv)
-
-	// execute file
-	if len(cmdArgs) > 1 {
-		rep(`(load-file "`+cmdArgs[1]+`")`, replEnv)
-		return nil
+	if err != nil {
+		return 1, err
 	}
+	defer editor.Close()
 
 	// repl
 	for {
+		editor.SetPrompt(in.Prompt())
 		inputBuffer, err := editor.Readline()
 		switch err {
 		case nil:
@@ -64,22 +181,30 @@ func run() error {
 			}
 
 			// editor.AddHistory(inputBuffer)
-			if result := rep(inputBuffer, replEnv); result != "()" {
-				fmt.Println(result)
+			ctx, stop := interruptible()
+			res, err := in.EvalLine(ctx, inputBuffer)
+			stop()
+			if exit := (*interp.ExitError)(nil); errors.As(err, &exit) {
+				return exit.Code, nil
+			}
+			if s, ok := interp.Display(res); ok {
+				fmt.Println(s)
 			}
 		case readline.ErrInterrupt:
-			fmt.Println("CTRL-C")
-			return nil
+			// drop line being edited
+			continue
 		case io.EOF:
-			return nil
+			return 0, nil
 		default:
-			return err
+			return 1, err
 		}
 	}
 }
 
 func main() {
-	if err := run(); err != nil {
+	status, err := run()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 	}
+	os.Exit(status)
 }
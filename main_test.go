@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rprtr258/lish/interp"
+)
+
+func TestInterrupt(t *testing.T) {
+	ctx, stop := interruptible()
+	defer stop()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+	_, err := interp.New().EvalLine(ctx, `(sleep 5)`)
+	assert.EqualError(t, err, "cancelled: interrupted")
+}
+
+func TestParseOptions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args []string
+		opts bool
+		rest []string
+		err  string
+	}{
+		"none":       {[]string{"-c", "(+ 1 2)"}, false, []string{"-c", "(+ 1 2)"}, ""},
+		"script":     {[]string{"main.lish", "--sandbox"}, false, []string{"main.lish", "--sandbox"}, ""},
+		"sandbox":    {[]string{"--sandbox", "main.lish"}, true, []string{"main.lish"}, ""},
+		"values":     {[]string{"--sandbox", "--allow-exec=git,ls", "--max-steps", "100", "-c", "1"}, true, []string{"-c", "1"}, ""},
+		"bytecode":   {[]string{"--bytecode", "main.lish"}, true, []string{"main.lish"}, ""},
+		"separator":  {[]string{"--max-memory=1024", "--", "--script"}, true, []string{"--script"}, ""},
+		"no_value":   {[]string{"--max-steps"}, false, nil, "--max-steps requires an argument"},
+		"bad_number": {[]string{"--max-steps=many"}, false, nil, `--max-steps: strconv.Atoi: parsing "many": invalid syntax`},
+		"unknown":    {[]string{"--bogus", "-c", "1"}, false, nil, "unknown option --bogus"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts, rest, err := parseOptions(tc.args)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.opts, len(opts) > 0)
+			assert.Equal(t, tc.rest, rest)
+		})
+	}
+}